@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"bulut-cli/pkg/client"
+	"fmt"
+)
+
+// newClient returns an API client for the current server, authenticated with
+// its saved API key or session.
+func newClient() (*client.Client, error) {
+	return newClientFor(getServerURL())
+}
+
+func newClientFor(serverURL string) (*client.Client, error) {
+	c, err := client.New(client.Options{
+		BaseURL: serverURL,
+		TokenFunc: func() (string, error) {
+			return getApiKeyForServer(serverURL)
+		},
+		UserAgent: "bulut-cli/" + version,
+	})
+	if err != nil {
+		return nil, newError(codeConfig, "%v", err)
+	}
+	if err := checkCompatibility(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// fromAPIError turns an error of the server into an error with the code of
+// its status, keeping the code of the server as api_code.
+func fromAPIError(apiErr *client.Error, err error) *cliError {
+	return &cliError{
+		Code:      statusCode(apiErr.Status),
+		Message:   err.Error(),
+		Status:    apiErr.Status,
+		APICode:   apiErr.Code,
+		Details:   apiErr.Details,
+		RequestID: apiErr.RequestID,
+		Hint:      apiErrorHint(apiErr.Code, apiErr.RequestID),
+		err:       err,
+	}
+}
+
+// apiErrorHint tells the user what to do about an error of the server.
+func apiErrorHint(code, requestID string) string {
+	switch code {
+	case client.CodeUnauthorized:
+		return "log in again with `bulut login`"
+	case client.CodeTokenExpired:
+		return "your token expired, log in again with `bulut login` or ask an admin for a new token"
+	case client.CodeTokenRevoked:
+		return "your token was revoked, ask an admin for a new one"
+	case client.CodeForbidden:
+		return "ask an owner of the namespace or an admin for access, `bulut whoami` shows who you are logged in as"
+	case client.CodeNamespaceNotEmpty:
+		return "delete its deployments first, or pass --cascade to delete them with the namespace"
+	case client.CodeLastOwner:
+		return "make another member owner first"
+	case client.CodeNotConfigured:
+		return "ask the admin of the server to configure it"
+	case client.CodeInternal, client.CodeUpstream:
+		if requestID != "" {
+			return fmt.Sprintf("the server failed, report request %s to its admin", requestID)
+		}
+		return "the server failed, try again or report it to its admin"
+	}
+	return ""
+}
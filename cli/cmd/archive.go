@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+func generateTempFilename() string {
+	rand.Seed(time.Now().UnixNano())
+	randID := fmt.Sprintf("%016x", rand.Uint64())
+	return filepath.Join(os.TempDir(), "deployment-"+randID)
+}
+
+func zipDirectory(dirPath, zipFilePath string) error {
+	zipFile, err := os.Create(zipFilePath)
+	if err != nil {
+		return err
+	}
+	defer zipFile.Close()
+
+	zipWriter := zip.NewWriter(zipFile)
+	defer zipWriter.Close()
+
+	err = filepath.Walk(dirPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		pathInZip, err := filepath.Rel(dirPath, path)
+		if err != nil {
+			return err
+		}
+		if len(pathInZip) < 1 {
+			return nil
+		}
+		if pathInZip[0] != '/' {
+			pathInZip = "/" + pathInZip
+		}
+
+		if info.IsDir() {
+			pathInZip = fmt.Sprintf("%s%c", pathInZip, os.PathSeparator)
+			_, err = zipWriter.Create(pathInZip)
+			return err
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		zipFile, err := zipWriter.Create(pathInZip)
+		if err != nil {
+			return err
+		}
+
+		_, err = io.Copy(zipFile, file)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"bulut-cli/pkg/client"
+	"fmt"
+	"github.com/spf13/cobra"
+	"os"
+	"text/tabwriter"
+)
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Show who changed what on the server (requires the admin scope)",
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return checkLogin()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return showAudit(cmd, args)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(auditCmd)
+	auditCmd.Flags().String("actor", "", "Only show actions of this user")
+	auditCmd.Flags().StringP("namespace", "n", "", "Only show actions in this namespace")
+	auditCmd.Flags().String("deployment", "", "Only show actions on this deployment")
+	auditCmd.Flags().String("action", "", "Only show this action, for example deployment.upload")
+	auditCmd.Flags().String("since", "", "Only show actions since a duration (24h) or time (RFC3339)")
+	auditCmd.Flags().String("until", "", "Only show actions until a duration ago or time")
+	auditCmd.Flags().Int("page", 1, "Page to show")
+	auditCmd.Flags().Int("per-page", 50, "Entries per page")
+}
+
+func showAudit(cmd *cobra.Command, args []string) error {
+	opts := client.ListAuditOptions{}
+	opts.Actor, _ = cmd.Flags().GetString("actor")
+	opts.Namespace, _ = cmd.Flags().GetString("namespace")
+	opts.Deployment, _ = cmd.Flags().GetString("deployment")
+	opts.Action, _ = cmd.Flags().GetString("action")
+	opts.Since, _ = cmd.Flags().GetString("since")
+	opts.Until, _ = cmd.Flags().GetString("until")
+	opts.Page, _ = cmd.Flags().GetInt("page")
+	opts.PerPage, _ = cmd.Flags().GetInt("per-page")
+
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+	resp, err := c.ListAudit(commandContext(), opts)
+	if err != nil {
+		return err
+	}
+
+	return printResult(resp, func() error {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "TIME\tACTOR\tACTION\tTARGET\tRESULT\tREVISION\tSOURCE")
+		for _, e := range resp.Entries {
+			target := e.Namespace
+			if e.Deployment != "" {
+				target += "/" + e.Deployment
+			}
+			result := e.Result
+			if e.Status != 0 {
+				result = fmt.Sprintf("%s (%d)", result, e.Status)
+			}
+			revision := "-"
+			if e.RevisionID != nil {
+				revision = *e.RevisionID
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", e.CreatedAt.Local().Format("2006-01-02 15:04:05"),
+				e.Actor, e.Action, target, result, revision, e.SourceIP)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		fmt.Printf("Page %d of %d, %d entries\n", resp.Page, resp.Pages, resp.Total)
+		return nil
+	})
+}
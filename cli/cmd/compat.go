@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"bulut-cli/pkg/client"
+	"errors"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// How long the outcome of a compatibility check of a server is reused
+const compatibilityCheckTTL = 24 * time.Hour
+
+// serverCheck is the outcome of the last compatibility check of a server.
+type serverCheck struct {
+	CheckedAt time.Time `yaml:"checked_at"`
+	// Version of the CLI that checked, a new CLI checks again
+	CLIVersion    string `yaml:"cli_version"`
+	ServerVersion string `yaml:"server_version,omitempty"`
+	// The server is from before versioning and serves unversioned paths
+	Legacy bool `yaml:"legacy,omitempty"`
+}
+
+// checkCompatibility makes sure the server serves the API version of the CLI
+// on first contact, refusing servers that do not and warning about servers
+// that are older or newer than the CLI. The outcome is remembered for a day
+// so commands do not ask every time. BULUT_SKIP_VERSION_CHECK=1 skips it.
+func checkCompatibility(c *client.Client) error {
+	if os.Getenv("BULUT_SKIP_VERSION_CHECK") != "" {
+		return nil
+	}
+
+	checks := loadServerChecks()
+	if check, ok := checks[c.BaseURL()]; ok && check.CLIVersion == version && time.Since(check.CheckedAt) < compatibilityCheckTTL {
+		if check.Legacy {
+			c.UseLegacyPaths()
+		}
+		return nil
+	}
+
+	server, err := c.Negotiate(commandContext())
+	var incompatible *client.IncompatibleError
+	if errors.As(err, &incompatible) {
+		hint := "upgrade the server, or use an older version of the CLI"
+		if incompatible.Server.API.Min > client.APIVersion {
+			hint = "upgrade the CLI"
+		}
+		return &cliError{Code: codeIncompatible, Message: err.Error(), Hint: hint, err: err}
+	}
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case server.Legacy:
+		warnf("the server %s does not report its version, it is older than this CLI and may lack some of its features. Upgrade the server.", c.BaseURL())
+	case server.API.Max > client.APIVersion:
+		warnf("the server %s serves the newer API v%d, upgrade the CLI to use its features", c.BaseURL(), server.API.Max)
+	}
+
+	checks[c.BaseURL()] = serverCheck{
+		CheckedAt:     time.Now(),
+		CLIVersion:    version,
+		ServerVersion: server.Version,
+		Legacy:        server.Legacy,
+	}
+	// Failing to remember only means checking again next time
+	_ = saveServerChecks(checks)
+	return nil
+}
+
+func serverChecksPath() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cacheDir, "bulut", "servers.yaml"), nil
+}
+
+// loadServerChecks returns the remembered checks by server URL, none if they
+// cannot be read.
+func loadServerChecks() map[string]serverCheck {
+	checks := make(map[string]serverCheck)
+	path, err := serverChecksPath()
+	if err != nil {
+		return checks
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return checks
+	}
+	if err := yaml.Unmarshal(data, &checks); err != nil || checks == nil {
+		return make(map[string]serverCheck)
+	}
+	return checks
+}
+
+func saveServerChecks(checks map[string]serverCheck) error {
+	path, err := serverChecksPath()
+	if err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(checks)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
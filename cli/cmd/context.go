@@ -0,0 +1,335 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// Name of the context selected with --context
+var contextFlag string
+
+var contextCmd = &cobra.Command{
+	Use:   "context",
+	Short: "Manage the Bulut servers the CLI talks to",
+	Long: `Contexts are named servers with a default namespace. The context in use
+is chosen, in order, by the --context flag, the context key of .bulut.yaml
+and the one selected with "bulut context use". Without any context the
+server.host, server.port and server.ssl config keys are used.
+
+Logins are saved per server, so contexts of the same server share them.`,
+}
+
+var contextAddCmd = &cobra.Command{
+	Use:   "add <name>",
+	Short: "Add or update a context",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return addContext(cmd, args)
+	},
+}
+
+var contextUseCmd = &cobra.Command{
+	Use:   "use <name>",
+	Short: "Use a context by default",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return useContext(args)
+	},
+}
+
+var contextListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List contexts",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return listContexts(args)
+	},
+}
+
+var contextRemoveCmd = &cobra.Command{
+	Use:   "remove <name>",
+	Short: "Remove a context",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return removeContext(args)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(contextCmd)
+	contextCmd.AddCommand(contextAddCmd)
+	contextCmd.AddCommand(contextUseCmd)
+	contextCmd.AddCommand(contextListCmd)
+	contextCmd.AddCommand(contextRemoveCmd)
+
+	rootCmd.PersistentFlags().StringVar(&contextFlag, "context", "", "Name of the context to use")
+	contextAddCmd.Flags().String("server", "", "URL of the Bulut server, for example https://bulut.example.com")
+	contextAddCmd.Flags().String("namespace", "", "Namespace used when deployment.namespace is not set")
+	contextAddCmd.Flags().Bool("use", false, "Also use the context by default")
+	_ = contextAddCmd.MarkFlagRequired("server")
+}
+
+type cliContext struct {
+	Server    string `yaml:"server"`
+	Namespace string `yaml:"namespace,omitempty"`
+}
+
+// contextEntry is a context as printed by the context commands.
+type contextEntry struct {
+	Name      string `json:"name"`
+	Server    string `json:"server"`
+	Namespace string `json:"namespace,omitempty"`
+	Current   bool   `json:"current"`
+}
+
+type contextsFile struct {
+	Current  string                `yaml:"current,omitempty"`
+	Contexts map[string]cliContext `yaml:"contexts"`
+}
+
+func contextsFilePath() (string, error) {
+	if path := os.Getenv("BULUT_CONTEXTS_FILE"); path != "" {
+		return path, nil
+	}
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "bulut", "contexts.yaml"), nil
+}
+
+func loadContexts() (*contextsFile, error) {
+	contexts := &contextsFile{Contexts: make(map[string]cliContext)}
+	path, err := contextsFilePath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return contexts, nil
+		}
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, contexts); err != nil {
+		return nil, &cliError{Code: codeConfig, Message: fmt.Sprintf("invalid contexts file %s: %v", path, err), err: err}
+	}
+	if contexts.Contexts == nil {
+		contexts.Contexts = make(map[string]cliContext)
+	}
+	return contexts, nil
+}
+
+func saveContexts(contexts *contextsFile) error {
+	path, err := contextsFilePath()
+	if err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(contexts)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// getCurrentContext returns the name and context in use, or an empty name
+// when no context is used.
+func getCurrentContext() (string, *cliContext, error) {
+	name := contextFlag
+	if name == "" {
+		name = viper.GetString("context")
+	}
+	contexts, err := loadContexts()
+	if err != nil {
+		return "", nil, err
+	}
+	if name == "" {
+		name = contexts.Current
+	}
+	if name == "" {
+		return "", nil, nil
+	}
+	context, ok := contexts.Contexts[name]
+	if !ok {
+		return "", nil, newError(codeConfig, "context %s does not exist, see `bulut context list`", name)
+	}
+	return name, &context, nil
+}
+
+// getServerURL returns the server of the context in use, or the one of the
+// config.
+func getServerURL() string {
+	_, context, err := getCurrentContext()
+	checkErr(err)
+	if context != nil {
+		return context.Server
+	}
+
+	host := viper.GetString("server.host")
+	port := viper.GetUint("server.port")
+	ssl := viper.GetBool("server.ssl")
+
+	protocol := "http"
+	if ssl {
+		protocol = "https"
+	}
+
+	return fmt.Sprintf("%s://%s:%d", protocol, host, port)
+}
+
+// getDefaultNamespace returns deployment.namespace of the config, or the
+// namespace of the context in use.
+func getDefaultNamespace() string {
+	if namespace := viper.GetString("deployment.namespace"); namespace != "" {
+		return namespace
+	}
+	_, context, err := getCurrentContext()
+	if err != nil || context == nil {
+		return ""
+	}
+	return context.Namespace
+}
+
+func addContext(cmd *cobra.Command, args []string) error {
+	server, _ := cmd.Flags().GetString("server")
+	namespace, _ := cmd.Flags().GetString("namespace")
+	use, _ := cmd.Flags().GetBool("use")
+
+	server = strings.TrimSuffix(server, "/")
+	u, err := url.Parse(server)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return newError(codeInvalidArgument, "invalid server URL: %s", server)
+	}
+
+	contexts, err := loadContexts()
+	if err != nil {
+		return err
+	}
+	contexts.Contexts[args[0]] = cliContext{Server: server, Namespace: namespace}
+	if use || contexts.Current == "" {
+		contexts.Current = args[0]
+	}
+	if err := saveContexts(contexts); err != nil {
+		return err
+	}
+	result := contextEntry{
+		Name:      args[0],
+		Server:    server,
+		Namespace: namespace,
+		Current:   contexts.Current == args[0],
+	}
+	return printResult(result, func() error {
+		fmt.Printf("Context %s saved\n", args[0])
+		if result.Current {
+			fmt.Printf("Using context %s\n", args[0])
+		}
+		return nil
+	})
+}
+
+func useContext(args []string) error {
+	contexts, err := loadContexts()
+	if err != nil {
+		return err
+	}
+	context, ok := contexts.Contexts[args[0]]
+	if !ok {
+		return newError(codeNotFound, "context %s does not exist", args[0])
+	}
+	contexts.Current = args[0]
+	if err := saveContexts(contexts); err != nil {
+		return err
+	}
+	result := contextEntry{Name: args[0], Server: context.Server, Namespace: context.Namespace, Current: true}
+	return printResult(result, func() error {
+		fmt.Printf("Using context %s\n", args[0])
+		return nil
+	})
+}
+
+func listContexts(args []string) error {
+	contexts, err := loadContexts()
+	if err != nil {
+		return err
+	}
+	current, _, err := getCurrentContext()
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(contexts.Contexts))
+	for name := range contexts.Contexts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	entries := make([]contextEntry, 0, len(names))
+	for _, name := range names {
+		context := contexts.Contexts[name]
+		entries = append(entries, contextEntry{
+			Name:      name,
+			Server:    context.Server,
+			Namespace: context.Namespace,
+			Current:   name == current,
+		})
+	}
+
+	return printResult(entries, func() error {
+		if len(entries) == 0 {
+			fmt.Println("No contexts, add one with `bulut context add`")
+			return nil
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "CURRENT\tNAME\tSERVER\tNAMESPACE")
+		for _, entry := range entries {
+			marker := ""
+			if entry.Current {
+				marker = "*"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", marker, entry.Name, entry.Server, entry.Namespace)
+		}
+		return w.Flush()
+	})
+}
+
+func removeContext(args []string) error {
+	contexts, err := loadContexts()
+	if err != nil {
+		return err
+	}
+	if _, ok := contexts.Contexts[args[0]]; !ok {
+		return newError(codeNotFound, "context %s does not exist", args[0])
+	}
+	delete(contexts.Contexts, args[0])
+	if contexts.Current == args[0] {
+		contexts.Current = ""
+	}
+	if err := saveContexts(contexts); err != nil {
+		return err
+	}
+	return printResult(map[string]string{"name": args[0]}, func() error {
+		fmt.Printf("Context %s removed\n", args[0])
+		return nil
+	})
+}
+
+// getConfiguredDeployment returns the namespace and name of the deployment
+// of the config.
+func getConfiguredDeployment() (string, string, error) {
+	namespace := getDefaultNamespace()
+	deploymentName := viper.GetString("deployment.name")
+	if namespace == "" || deploymentName == "" {
+		return "", "", newError(codeConfig, "deployment.name and deployment.namespace (or a context namespace) must be set in the config")
+	}
+	return namespace, deploymentName, nil
+}
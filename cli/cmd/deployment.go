@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"bulut-cli/pkg/client"
+	"fmt"
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/spf13/cobra"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+var deploymentCmd = &cobra.Command{
+	Use:     "deployment",
+	Aliases: []string{"dep"},
+	Short:   "Manage deployments",
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return checkLogin()
+	},
+}
+
+var deploymentListCmd = &cobra.Command{
+	Use:     "list [namespace]",
+	Aliases: []string{"ls"},
+	Short:   "List the deployments of a namespace with their status",
+	Long: `List the deployments of a namespace with the live status of their
+replicas. Without an argument the namespace of the config or the current
+context is used.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return listDeployments(cmd, args)
+	},
+}
+
+var deploymentDeleteCmd = &cobra.Command{
+	Use:   "delete [namespace/deployment]",
+	Short: "Delete a deployment with its containers, images and logs",
+	Long: `Delete a deployment with its containers, images and logs. Without an
+argument the deployment of the config is deleted.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return deleteDeployment(cmd, args)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(deploymentCmd)
+	deploymentCmd.AddCommand(deploymentListCmd)
+	deploymentCmd.AddCommand(deploymentDeleteCmd)
+
+	deploymentListCmd.Flags().String("name", "", "Only show deployments with this in their name")
+	deploymentListCmd.Flags().String("status", "", "Only show deployments with this status: running, degraded, stopped, crash-looping, not-deployed or unknown")
+	deploymentListCmd.Flags().Int("page", 1, "Page to show")
+	deploymentListCmd.Flags().Int("per-page", 50, "Deployments per page")
+
+	deploymentDeleteCmd.Flags().Bool("volumes", false, "Also remove the volumes of the deployment and its containers")
+	deploymentDeleteCmd.Flags().BoolP("yes", "y", false, "Do not ask for confirmation")
+}
+
+// parseDeploymentArg reads a namespace/deployment argument, or the configured
+// deployment if there is none.
+func parseDeploymentArg(args []string) (string, string, error) {
+	if len(args) == 0 {
+		return getConfiguredDeployment()
+	}
+	namespace, deploymentName, ok := strings.Cut(args[0], "/")
+	if !ok || namespace == "" || deploymentName == "" {
+		return "", "", newError(codeInvalidArgument, "deployment must be given as namespace/deployment")
+	}
+	return namespace, deploymentName, nil
+}
+
+// confirm asks the user to confirm a destructive action, unless --yes is set.
+// It returns an aborted error if the user declines.
+func confirm(cmd *cobra.Command, message string) error {
+	if yes, _ := cmd.Flags().GetBool("yes"); yes {
+		return nil
+	}
+	var confirmation bool
+	err := ask(&survey.Confirm{Message: message}, &confirmation, "pass --yes to confirm")
+	if err != nil {
+		return err
+	}
+	if !confirmation {
+		return newError(codeAborted, "aborted by the user")
+	}
+	return nil
+}
+
+func listDeployments(cmd *cobra.Command, args []string) error {
+	namespace := getDefaultNamespace()
+	if len(args) > 0 {
+		namespace = args[0]
+	}
+	if namespace == "" {
+		return newError(codeConfig, "no namespace given and none configured")
+	}
+
+	opts := client.ListDeploymentsOptions{}
+	opts.Name, _ = cmd.Flags().GetString("name")
+	opts.Status, _ = cmd.Flags().GetString("status")
+	opts.Page, _ = cmd.Flags().GetInt("page")
+	opts.PerPage, _ = cmd.Flags().GetInt("per-page")
+
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+	resp, err := c.ListDeployments(commandContext(), namespace, opts)
+	if err != nil {
+		return err
+	}
+	return printResult(resp, func() error {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tSTATUS\tREPLICAS\tRESTARTS\tUPDATED")
+		for _, dep := range resp.Deployments {
+			fmt.Fprintf(w, "%s\t%s\t%d/%d\t%d\t%s\n", dep.Name, dep.Status, dep.Running, dep.Replicas,
+				dep.RestartCount, formatTime(&dep.UpdatedAt))
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		if resp.Pages > 1 {
+			fmt.Printf("Page %d of %d, %d deployments\n", resp.Page, resp.Pages, resp.Total)
+		}
+		return nil
+	})
+}
+
+func deleteDeployment(cmd *cobra.Command, args []string) error {
+	namespace, deploymentName, err := parseDeploymentArg(args)
+	if err != nil {
+		return err
+	}
+	volumes, _ := cmd.Flags().GetBool("volumes")
+
+	message := fmt.Sprintf("Delete deployment %s/%s with its containers, images and logs?", namespace, deploymentName)
+	if volumes {
+		message = fmt.Sprintf("Delete deployment %s/%s with its containers, images, logs and volumes?", namespace, deploymentName)
+	}
+	if err := confirm(cmd, message); err != nil {
+		return err
+	}
+
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+	if err := c.DeleteDeployment(commandContext(), namespace, deploymentName, volumes); err != nil {
+		return err
+	}
+	result := map[string]string{"namespace": namespace, "deployment": deploymentName}
+	return printResult(result, func() error {
+		fmt.Printf("Deployment %s/%s deleted\n", namespace, deploymentName)
+		return nil
+	})
+}
@@ -8,39 +8,65 @@ import (
 	"fmt"
 	"github.com/AlecAivazis/survey/v2"
 	"github.com/spf13/cobra"
+	"os"
+	"time"
 )
 
 var loginCmd = &cobra.Command{
 	Use:   "login",
 	Short: "Login to the Bulut server",
+	Long: `Login to the Bulut server with an API key or token.
+
+With --sso, login through the identity provider of the server instead. The
+session is renewed automatically until its refresh token expires.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		return login(args)
 	},
 }
 
+var loginSSOFlag bool
+
 func init() {
 	rootCmd.AddCommand(loginCmd)
+	loginCmd.Flags().BoolVar(&loginSSOFlag, "sso", false, "Login through the identity provider of the server")
+}
+
+type loginResult struct {
+	Server          string `json:"server"`
+	AlreadyLoggedIn bool   `json:"already_logged_in"`
+	// Only set for SSO logins
+	User      string     `json:"user,omitempty"`
+	Scopes    string     `json:"scopes,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
 }
 
 func login(args []string) error {
 	// Check if the user is already logged in
 	serverURL := getServerURL()
-	apiKey, err := getApiKeyForServer(serverURL)
+	// An expired session must not stop the user from logging in again
+	apiKey, err := ring.Get(fmt.Sprintf("api-key_%s", serverURL))
 	if err != nil && !errors.Is(err, ring.ErrNotFound) {
 		return err
 	}
 	if apiKey != "" {
-		fmt.Println("You are already logged in!")
-		fmt.Println("If you want to login with different credentials, logout first.")
-		fmt.Println("To see your login info, run `bulut whoami`")
-		return nil
+		result := loginResult{Server: serverURL, AlreadyLoggedIn: true}
+		return printResult(result, func() error {
+			fmt.Println("You are already logged in!")
+			fmt.Println("If you want to login with different credentials, logout first.")
+			fmt.Println("To see your login info, run `bulut whoami`")
+			return nil
+		})
+	}
+
+	if loginSSOFlag {
+		return loginSSO(serverURL)
 	}
 
 	// Get the API key from the user
 	prompt := &survey.Password{
-		Message: "Enter your API Key",
+		Message: "Enter your API Key or token",
 	}
-	err = survey.AskOne(prompt, &apiKey, survey.WithValidator(survey.Required))
+	err = ask(prompt, &apiKey, "set BULUT_TOKEN to use a token without logging in", survey.WithValidator(survey.Required))
 	if err != nil {
 		return err
 	}
@@ -48,11 +74,43 @@ func login(args []string) error {
 	if err != nil {
 		return err
 	}
-	fmt.Println("Key saved to keyring successfully!")
-	return nil
+	return printResult(loginResult{Server: serverURL}, func() error {
+		fmt.Println("Key saved to keyring successfully!")
+		return nil
+	})
 }
 
 func saveToken(serverURL, apiKey string) error {
 	keyName := fmt.Sprintf("api-key_%s", serverURL)
 	return ring.Set(keyName, apiKey)
 }
+
+// getApiKeyForServer returns BULUT_TOKEN, or the token saved for the server
+// after renewing its session if needed.
+func getApiKeyForServer(server string) (string, error) {
+	// Used as is for non-interactive use like CI
+	if token := os.Getenv("BULUT_TOKEN"); token != "" {
+		return token, nil
+	}
+	if err := refreshSessionIfNeeded(server); err != nil {
+		return "", err
+	}
+	apiKey, err := ring.Get(fmt.Sprintf("api-key_%s", server))
+	if err != nil {
+		return "", err
+	}
+	return apiKey, nil
+}
+
+// checkLogin fails with codeNotLoggedIn if there is no token for the server.
+func checkLogin() error {
+	serverURL := getServerURL()
+	_, err := getApiKeyForServer(serverURL)
+	if err != nil {
+		if errors.Is(err, ring.ErrNotFound) {
+			return newError(codeNotLoggedIn, "you are not logged in to %s", serverURL)
+		}
+		return err
+	}
+	return nil
+}
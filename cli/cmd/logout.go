@@ -23,14 +23,21 @@ func init() {
 
 func logout(args []string) error {
 	serverURL := getServerURL()
+	deleteSession(serverURL)
 	err := ring.Delete(fmt.Sprintf("api-key_%s", serverURL))
-	if err != nil {
-		if errors.Is(err, ring.ErrNotFound) {
-			fmt.Println("You are not logged in!")
-			return nil
-		}
+	if err != nil && !errors.Is(err, ring.ErrNotFound) {
 		return err
 	}
-	fmt.Println("Logged out successfully!")
-	return nil
+	result := map[string]interface{}{
+		"server":     serverURL,
+		"logged_out": err == nil,
+	}
+	return printResult(result, func() error {
+		if err != nil {
+			fmt.Println("You are not logged in!")
+		} else {
+			fmt.Println("Logged out successfully!")
+		}
+		return nil
+	})
 }
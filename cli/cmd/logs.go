@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"bulut-cli/pkg/client"
+	"encoding/json"
+	"fmt"
+	"github.com/spf13/cobra"
+	"io"
+	"os"
+	"time"
+)
+
+var logsCmd = &cobra.Command{
+	Use:   "logs",
+	Short: "Show the output of the deployment",
+	Long: `Show the output of all replicas of the deployment, each line prefixed with its container.
+
+With --revision, --grep, --regex or --until the logs stored on the server are
+searched instead, which includes the output of replicas of previous revisions.`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return checkLogin()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return showLogs(cmd, args)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(logsCmd)
+	logsCmd.Flags().BoolP("follow", "f", false, "Keep streaming new output")
+	logsCmd.Flags().String("since", "", "Only show output since a duration (10m) or time (RFC3339)")
+	logsCmd.Flags().String("tail", "all", "Number of lines to show from the end of each replica's output")
+	logsCmd.Flags().BoolP("timestamps", "t", false, "Show timestamps")
+	logsCmd.Flags().String("revision", "", "Show stored logs of a revision, by ID or image tag")
+	logsCmd.Flags().String("grep", "", "Only show stored lines containing this text")
+	logsCmd.Flags().String("regex", "", "Only show stored lines matching this regular expression")
+	logsCmd.Flags().String("until", "", "Only show stored output until a duration ago (10m) or time (RFC3339)")
+	logsCmd.Flags().Int("limit", 1000, "Maximum number of stored lines to show")
+}
+
+func showLogs(cmd *cobra.Command, args []string) error {
+	follow, _ := cmd.Flags().GetBool("follow")
+	since, _ := cmd.Flags().GetString("since")
+	tail, _ := cmd.Flags().GetString("tail")
+	timestamps, _ := cmd.Flags().GetBool("timestamps")
+
+	namespace, deploymentName, err := getConfiguredDeployment()
+	if err != nil {
+		return err
+	}
+
+	flags := cmd.Flags()
+	if flags.Changed("revision") || flags.Changed("grep") || flags.Changed("regex") || flags.Changed("until") {
+		if follow {
+			return newError(codeInvalidArgument, "--follow cannot be used when searching stored logs")
+		}
+		return showStoredLogs(cmd, namespace, deploymentName)
+	}
+
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+	stream, err := c.Logs(commandContext(), namespace, deploymentName, client.LogsOptions{
+		Follow:     follow,
+		Since:      since,
+		Tail:       tail,
+		Timestamps: timestamps,
+	})
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	return printLogLines(stream)
+}
+
+// printLogLines prints the lines as they are streamed. With structured output
+// each line is a JSON object on its own line, or a YAML document.
+func printLogLines(stream *client.LogStream) error {
+	enc := json.NewEncoder(os.Stdout)
+	for {
+		line, err := stream.Next()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		switch outputFlag {
+		case outputJSON:
+			if err := enc.Encode(line); err != nil {
+				return err
+			}
+			continue
+		case outputYAML:
+			fmt.Println("---")
+			if err := encodeOutput(os.Stdout, line); err != nil {
+				return err
+			}
+			continue
+		}
+
+		out := os.Stdout
+		if line.Stream == "stderr" {
+			out = os.Stderr
+		}
+		if line.Time != nil {
+			fmt.Fprintf(out, "[%s] %s %s\n", line.Container, line.Time.Local().Format(time.RFC3339), line.Text)
+		} else {
+			fmt.Fprintf(out, "[%s] %s\n", line.Container, line.Text)
+		}
+	}
+}
+
+func showStoredLogs(cmd *cobra.Command, namespace, deploymentName string) error {
+	opts := client.LogHistoryOptions{}
+	opts.Revision, _ = cmd.Flags().GetString("revision")
+	opts.Since, _ = cmd.Flags().GetString("since")
+	opts.Until, _ = cmd.Flags().GetString("until")
+	opts.Regex, _ = cmd.Flags().GetString("regex")
+	opts.Contains, _ = cmd.Flags().GetString("grep")
+	opts.Limit, _ = cmd.Flags().GetInt("limit")
+
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+	stream, err := c.LogHistory(commandContext(), namespace, deploymentName, opts)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	return printLogLines(stream)
+}
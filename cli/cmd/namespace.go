@@ -4,13 +4,12 @@ Copyright © 2023 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
-	"bytes"
-	"errors"
+	"bulut-cli/pkg/client"
 	"fmt"
 	"github.com/AlecAivazis/survey/v2"
 	"github.com/spf13/cobra"
-	"io"
-	"net/http"
+	"os"
+	"text/tabwriter"
 )
 
 var namespaceCmd = &cobra.Command{
@@ -32,80 +31,138 @@ var nsCreateCmd = &cobra.Command{
 	},
 }
 
+var nsListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List the namespaces you are a member of, or all of them as an admin",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return listNamespaces(cmd)
+	},
+}
+
+var nsDeleteCmd = &cobra.Command{
+	Use:   "delete <namespace>",
+	Short: "Delete a namespace",
+	Long: `Delete a namespace. A namespace with deployments is only deleted with
+--cascade, which deletes all its deployments first.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return deleteNamespace(cmd, args)
+	},
+}
+
 func init() {
 	rootCmd.AddCommand(namespaceCmd)
 	namespaceCmd.AddCommand(nsCreateCmd)
+	namespaceCmd.AddCommand(nsListCmd)
+	namespaceCmd.AddCommand(nsDeleteCmd)
+
+	nsListCmd.Flags().String("name", "", "Only show namespaces with this in their name")
+	nsListCmd.Flags().Int("page", 1, "Page to show")
+	nsListCmd.Flags().Int("per-page", 50, "Namespaces per page")
+
+	nsDeleteCmd.Flags().Bool("cascade", false, "Also delete all deployments of the namespace")
+	nsDeleteCmd.Flags().Bool("volumes", false, "Also remove the volumes of the deployments and their containers")
+	nsDeleteCmd.Flags().BoolP("yes", "y", false, "Do not ask for confirmation")
 }
 
-func createNamespaceHandler(args []string) error {
-	name := ""
-	if len(args) > 0 {
-		name = args[0]
+func listNamespaces(cmd *cobra.Command) error {
+	opts := client.ListNamespacesOptions{}
+	opts.Name, _ = cmd.Flags().GetString("name")
+	opts.Page, _ = cmd.Flags().GetInt("page")
+	opts.PerPage, _ = cmd.Flags().GetInt("per-page")
+
+	c, err := newClient()
+	if err != nil {
+		return err
 	}
-	if name == "" {
-		prompt := &survey.Input{
-			Message: "New namespace name",
+	resp, err := c.ListNamespaces(commandContext(), opts)
+	if err != nil {
+		return err
+	}
+	return printResult(resp, func() error {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tDEPLOYMENTS\tCREATED")
+		for _, ns := range resp.Namespaces {
+			fmt.Fprintf(w, "%s\t%d\t%s\n", ns.Name, ns.Deployments, formatTime(&ns.CreatedAt))
 		}
-		err := survey.AskOne(prompt, &name)
-		if err != nil {
+		if err := w.Flush(); err != nil {
 			return err
 		}
-		if name == "" {
-			return fmt.Errorf("namespace name cannot be empty")
+		if resp.Pages > 1 {
+			fmt.Printf("Page %d of %d, %d namespaces\n", resp.Page, resp.Pages, resp.Total)
 		}
-	}
-	fmt.Printf("Creating namespace \"%s\"\n", name)
-	err := createNamespace(name)
-	return err
+		return nil
+	})
 }
 
-func createNamespace(name string) error {
-	serverURL := getServerURL()
-	apiKey, err := getApiKeyForServer(serverURL)
-	if err != nil {
-		return err
+func deleteNamespace(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	cascade, _ := cmd.Flags().GetBool("cascade")
+	volumes, _ := cmd.Flags().GetBool("volumes")
+
+	message := fmt.Sprintf("Delete namespace %s?", name)
+	if cascade {
+		message = fmt.Sprintf("Delete namespace %s and all of its deployments?", name)
 	}
-	body := &bytes.Buffer{}
-	_, err = body.WriteString(fmt.Sprintf(`{"name":"%s"}`, name))
-	if err != nil {
+	if err := confirm(cmd, message); err != nil {
 		return err
 	}
-	req, err := http.NewRequest("POST", serverURL+"/namespace/", body)
+
+	c, err := newClient()
 	if err != nil {
 		return err
 	}
-	req.Header.Set("Authorization", apiKey)
-	req.Header.Set("Content-Type", "application/json")
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	deleted, err := c.DeleteNamespace(commandContext(), name, client.DeleteNamespaceOptions{Cascade: cascade, Volumes: volumes})
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
+	result := struct {
+		Namespace          string `json:"namespace"`
+		DeletedDeployments int    `json:"deleted_deployments"`
+	}{name, deleted.DeletedDeployments}
+	return printResult(result, func() error {
+		if result.DeletedDeployments > 0 {
+			fmt.Printf("Namespace %s deleted with %d deployments\n", name, result.DeletedDeployments)
+		} else {
+			fmt.Printf("Namespace %s deleted\n", name)
+		}
+		return nil
+	})
+}
 
-	if resp.StatusCode != 200 {
-		errResp := &bytes.Buffer{}
-		_, err := io.Copy(errResp, resp.Body)
+func createNamespaceHandler(args []string) error {
+	name := ""
+	if len(args) > 0 {
+		name = args[0]
+	}
+	if name == "" {
+		prompt := &survey.Input{
+			Message: "New namespace name",
+		}
+		err := ask(prompt, &name, "pass the namespace name as an argument")
 		if err != nil {
 			return err
 		}
-
-		return fmt.Errorf("bad status: %s, response: %s", resp.Status, errResp)
+		if name == "" {
+			return newError(codeInvalidArgument, "namespace name cannot be empty")
+		}
 	}
-
-	return nil
+	progressf("Creating namespace \"%s\"\n", name)
+	if err := createNamespace(name); err != nil {
+		return err
+	}
+	return printResult(map[string]string{"namespace": name}, func() error {
+		fmt.Printf("Namespace %s created\n", name)
+		return nil
+	})
 }
 
-// TODO: Move to a common place
-func checkLogin() error {
-	serverURL := getServerURL()
-	_, err := getApiKeyForServer(serverURL)
+func createNamespace(name string) error {
+	c, err := newClient()
 	if err != nil {
-		if errors.Is(err, ring.ErrNotFound) {
-			err = fmt.Errorf("you are not logged in to %s", serverURL)
-			return err
-		}
 		return err
 	}
-	return nil
+	return c.CreateNamespace(commandContext(), name)
 }
@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"os"
+	"text/tabwriter"
+)
+
+var nsMembersCmd = &cobra.Command{
+	Use:   "members",
+	Short: "Manage who can access a namespace",
+	Long: `Members of a namespace have one of the roles:
+  viewer    read deployments and logs
+  deployer  create, deploy and scale deployments
+  owner     manage the members of the namespace`,
+}
+
+var nsMembersListCmd = &cobra.Command{
+	Use:   "list <namespace>",
+	Short: "List the members of a namespace",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return listMembers(args)
+	},
+}
+
+var nsMembersAddCmd = &cobra.Command{
+	Use:   "add <namespace> <user>",
+	Short: "Add a user to a namespace or change its role",
+	Long: `Add a user to a namespace or change its role. The user must have logged
+in or have a token, only admins can add users that do not exist yet.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return addMember(cmd, args)
+	},
+}
+
+var nsMembersRemoveCmd = &cobra.Command{
+	Use:   "remove <namespace> <user>",
+	Short: "Remove a user from a namespace",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return removeMember(args)
+	},
+}
+
+func init() {
+	namespaceCmd.AddCommand(nsMembersCmd)
+	nsMembersCmd.AddCommand(nsMembersListCmd)
+	nsMembersCmd.AddCommand(nsMembersAddCmd)
+	nsMembersCmd.AddCommand(nsMembersRemoveCmd)
+
+	nsMembersAddCmd.Flags().String("role", "deployer", "Role of the user: viewer, deployer or owner")
+}
+
+func listMembers(args []string) error {
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+	members, err := c.ListMembers(commandContext(), args[0])
+	if err != nil {
+		return err
+	}
+
+	return printResult(members, func() error {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "USER\tROLE\tSINCE")
+		for _, m := range members {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", m.User.Name, m.Role, m.CreatedAt.Local().Format("2006-01-02 15:04"))
+		}
+		return w.Flush()
+	})
+}
+
+func addMember(cmd *cobra.Command, args []string) error {
+	role, _ := cmd.Flags().GetString("role")
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+	if _, err := c.SetMember(commandContext(), args[0], args[1], role); err != nil {
+		return err
+	}
+	result := map[string]string{"namespace": args[0], "user": args[1], "role": role}
+	return printResult(result, func() error {
+		fmt.Printf("%s is now %s in namespace %s\n", args[1], role, args[0])
+		return nil
+	})
+}
+
+func removeMember(args []string) error {
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+	if err := c.RemoveMember(commandContext(), args[0], args[1]); err != nil {
+		return err
+	}
+	result := map[string]string{"namespace": args[0], "user": args[1]}
+	return printResult(result, func() error {
+		fmt.Printf("Removed %s from namespace %s\n", args[1], args[0])
+		return nil
+	})
+}
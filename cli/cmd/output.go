@@ -0,0 +1,259 @@
+package cmd
+
+import (
+	"bulut-cli/pkg/client"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/AlecAivazis/survey/v2"
+	"golang.org/x/term"
+	"gopkg.in/yaml.v3"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Formats of the --output flag
+const (
+	outputText = "text"
+	outputJSON = "json"
+	outputYAML = "yaml"
+)
+
+// outputFormat is the value of the --output flag, validated when it is set.
+type outputFormat string
+
+func (o *outputFormat) String() string {
+	return string(*o)
+}
+
+func (o *outputFormat) Set(value string) error {
+	switch value {
+	case outputText, outputJSON, outputYAML:
+		*o = outputFormat(value)
+		return nil
+	}
+	return fmt.Errorf("must be %s, %s or %s", outputText, outputJSON, outputYAML)
+}
+
+func (o *outputFormat) Type() string {
+	return "format"
+}
+
+var (
+	outputFlag         = outputFormat(outputText)
+	nonInteractiveFlag bool
+)
+
+// Error codes of structured errors. Scripts rely on them, so they must not
+// change once released.
+const (
+	codeError            = "error"
+	codeInvalidArgument  = "invalid_argument"
+	codeConfig           = "config_error"
+	codeNotLoggedIn      = "not_logged_in"
+	codeInputRequired    = "input_required"
+	codeAborted          = "aborted"
+	codeConnectionFailed = "connection_failed"
+	codeBadRequest       = "bad_request"
+	codeUnauthorized     = "unauthorized"
+	codeForbidden        = "forbidden"
+	codeNotFound         = "not_found"
+	codeConflict         = "conflict"
+	codeServerError      = "server_error"
+	codeIncompatible     = "incompatible_server"
+)
+
+// cliError is an error with a stable code, printed as is with --output json
+// or yaml.
+type cliError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// HTTP status of the server response the error comes from
+	Status int `json:"status,omitempty"`
+	// Error of the server, see client.Error
+	APICode   string                 `json:"api_code,omitempty"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+	RequestID string                 `json:"request_id,omitempty"`
+	// What the user can do about the error
+	Hint string `json:"hint,omitempty"`
+	err  error
+}
+
+func (e *cliError) Error() string {
+	return e.Message
+}
+
+func (e *cliError) Unwrap() error {
+	return e.err
+}
+
+func newError(code, format string, args ...interface{}) error {
+	return &cliError{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// toCLIError gives errors without a code the closest one.
+func toCLIError(err error) *cliError {
+	var cliErr *cliError
+	if errors.As(err, &cliErr) {
+		if cliErr == err {
+			return cliErr
+		}
+		// Keep the context wrapped around the error
+		wrapped := *cliErr
+		wrapped.Message = err.Error()
+		wrapped.err = err
+		return &wrapped
+	}
+	var apiErr *client.Error
+	if errors.As(err, &apiErr) {
+		return fromAPIError(apiErr, err)
+	}
+	code := codeError
+	var urlErr *url.Error
+	switch {
+	case errors.Is(err, ring.ErrNotFound):
+		code = codeNotLoggedIn
+	case errors.As(err, &urlErr):
+		code = codeConnectionFailed
+	case strings.HasPrefix(err.Error(), "unknown command"), strings.HasPrefix(err.Error(), "unknown flag"):
+		code = codeInvalidArgument
+	}
+	return &cliError{Code: code, Message: err.Error(), err: err}
+}
+
+// statusCode returns the error code of a server response status.
+func statusCode(status int) string {
+	switch {
+	case status == 401:
+		return codeUnauthorized
+	case status == 403:
+		return codeForbidden
+	case status == 404:
+		return codeNotFound
+	case status == 409:
+		return codeConflict
+	case status >= 500:
+		return codeServerError
+	case status >= 400:
+		return codeBadRequest
+	}
+	return codeError
+}
+
+// structuredOutput reports whether results are printed as JSON or YAML
+// instead of text.
+func structuredOutput() bool {
+	return outputFlag != outputText
+}
+
+// nonInteractive reports whether commands must fail instead of prompting,
+// which is the case with --non-interactive, BULUT_NON_INTERACTIVE or when
+// stdin is not a terminal.
+func nonInteractive() bool {
+	if nonInteractiveFlag {
+		return true
+	}
+	if value, err := strconv.ParseBool(os.Getenv("BULUT_NON_INTERACTIVE")); err == nil && value {
+		return true
+	}
+	return !term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// ask prompts the user, or fails with an input_required error telling how to
+// pass the answer without a prompt in non-interactive mode.
+func ask(prompt survey.Prompt, response interface{}, hint string, opts ...survey.AskOpt) error {
+	if nonInteractive() {
+		return newError(codeInputRequired, "input required in non-interactive mode: %s", hint)
+	}
+	return survey.AskOne(prompt, response, opts...)
+}
+
+// progressf prints what a command is doing. It goes to stderr with structured
+// output so stdout only has the result.
+func progressf(format string, args ...interface{}) {
+	out := os.Stdout
+	if structuredOutput() {
+		out = os.Stderr
+	}
+	fmt.Fprintf(out, format, args...)
+}
+
+// warnf prints a warning to stderr, apart from the result.
+func warnf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "Warning: "+format+"\n", args...)
+}
+
+// printResult prints the result of a command, v encoded as JSON or YAML with
+// structured output and the output of text otherwise. Keys are the JSON
+// field names in both formats.
+func printResult(v interface{}, text func() error) error {
+	if !structuredOutput() {
+		return text()
+	}
+	return encodeOutput(os.Stdout, v)
+}
+
+func encodeOutput(w io.Writer, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if outputFlag == outputJSON {
+		var indented strings.Builder
+		enc := json.NewEncoder(&indented)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(json.RawMessage(data)); err != nil {
+			return err
+		}
+		_, err = io.WriteString(w, indented.String())
+		return err
+	}
+
+	// JSON is YAML, decoding it into a node keeps the order of the keys
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return err
+	}
+	resetStyle(&node)
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(&node); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// resetStyle drops the flow style and quotes a node decoded from JSON has,
+// so it is printed as block YAML.
+func resetStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		resetStyle(child)
+	}
+}
+
+// printError prints an error as text, or as an error document with
+// structured output, to stderr.
+func printError(err error) {
+	cliErr := toCLIError(err)
+	if !structuredOutput() {
+		fmt.Fprintln(os.Stderr, "Error:", cliErr.Message)
+		if cliErr.Hint != "" {
+			fmt.Fprintln(os.Stderr, "Hint:", cliErr.Hint)
+		}
+		return
+	}
+	_ = encodeOutput(os.Stderr, map[string]*cliError{"error": cliErr})
+}
+
+// checkErr is cobra.CheckErr for code outside of commands, like config
+// loading, printing the error in the output format.
+func checkErr(err error) {
+	if err != nil {
+		printError(err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"bulut-cli/pkg/client"
+	"fmt"
+	"github.com/spf13/cobra"
+)
+
+var restartPolicyCmd = &cobra.Command{
+	Use:       "restart-policy <no|on-failure|always>",
+	Short:     "Set when the containers of the deployment are restarted",
+	Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	ValidArgs: []string{"no", "on-failure", "always"},
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return checkLogin()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setRestartPolicy(cmd, args)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(restartPolicyCmd)
+	restartPolicyCmd.Flags().Int("max-retries", 0, "Maximum restart attempts for on-failure, 0 for unlimited")
+}
+
+func setRestartPolicy(cmd *cobra.Command, args []string) error {
+	maxRetries, err := cmd.Flags().GetInt("max-retries")
+	if err != nil {
+		return err
+	}
+	namespace, deploymentName, err := getConfiguredDeployment()
+	if err != nil {
+		return err
+	}
+
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+	policy, err := c.SetRestartPolicy(commandContext(), namespace, deploymentName, client.RestartPolicy{
+		Policy:     args[0],
+		MaxRetries: maxRetries,
+	})
+	if err != nil {
+		return err
+	}
+	resp := struct {
+		Namespace  string `json:"namespace"`
+		Deployment string `json:"deployment"`
+		*client.RestartPolicy
+	}{namespace, deploymentName, policy}
+	return printResult(resp, func() error {
+		fmt.Printf("Restart policy of %s/%s set to %s\n", namespace, deploymentName, resp.Policy)
+		return nil
+	})
+}
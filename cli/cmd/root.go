@@ -5,7 +5,9 @@ package cmd
 
 import (
 	"bulut-cli/util/keyring"
+	"errors"
 	"fmt"
+	"github.com/AlecAivazis/survey/v2"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"os"
@@ -34,12 +36,37 @@ var rootCmd = &cobra.Command{
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
+	rootCmd.SilenceErrors = true
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		cmd.SilenceUsage = structuredOutput()
+		return &cliError{Code: codeInvalidArgument, Message: err.Error(), err: err}
+	})
+	wrapArgsErrors(rootCmd)
+
 	err := rootCmd.Execute()
 	if err != nil {
+		printError(err)
 		os.Exit(1)
 	}
 }
 
+// wrapArgsErrors gives the errors of the argument validators of all commands
+// the invalid_argument code.
+func wrapArgsErrors(cmd *cobra.Command) {
+	if validate := cmd.Args; validate != nil {
+		cmd.Args = func(cmd *cobra.Command, args []string) error {
+			if err := validate(cmd, args); err != nil {
+				cmd.SilenceUsage = structuredOutput()
+				return &cliError{Code: codeInvalidArgument, Message: err.Error(), err: err}
+			}
+			return nil
+		}
+	}
+	for _, child := range cmd.Commands() {
+		wrapArgsErrors(child)
+	}
+}
+
 func init() {
 	cobra.OnInitialize(initConfig)
 	cobra.OnInitialize(initKeyring)
@@ -50,6 +77,8 @@ func init() {
 
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.bulut.yaml)")
 	rootCmd.PersistentFlags().String("server", "http://localhost:8080", "URL of the Bulut Server")
+	rootCmd.PersistentFlags().VarP(&outputFlag, "output", "o", "Output format: text, json or yaml")
+	rootCmd.PersistentFlags().BoolVar(&nonInteractiveFlag, "non-interactive", false, "Fail instead of prompting (default when stdin is not a terminal or BULUT_NON_INTERACTIVE is set)")
 
 	viper.BindPFlag("server", rootCmd.Flags().Lookup("server"))
 }
@@ -62,7 +91,7 @@ func initConfig() {
 	} else {
 		// Check if there is a config in the current directory
 		currentDir, err := os.Getwd()
-		cobra.CheckErr(err)
+		checkErr(err)
 		for i := 0; i < 2; i++ {
 			var newDir string
 			var configFound bool
@@ -75,12 +104,12 @@ func initConfig() {
 			currentDir = newDir
 		}
 
-		cobra.CheckErr(err)
+		checkErr(err)
 	}
 
 	// Find home directory.
 	home, err := os.UserHomeDir()
-	cobra.CheckErr(err)
+	checkErr(err)
 
 	// Search config in home directory
 	viper.AddConfigPath(home)
@@ -97,16 +126,43 @@ func initConfig() {
 	viper.AutomaticEnv() // read in environment variables that match
 
 	// If a config file is found, read it in.
-	if err := viper.MergeInConfig(); err == nil {
+	if err := viper.MergeInConfig(); err == nil && !structuredOutput() {
 		fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
-		fmt.Println("Using server:", getServerURL())
+		fmt.Fprintln(os.Stderr, "Using server:", getServerURL())
 	}
 }
 
+// initKeyring opens the keyring of the OS, or an encrypted file where there is
+// none. BULUT_KEYRING forces a backend and BULUT_KEYRING_PASSPHRASE unlocks the
+// file without a prompt.
 func initKeyring() {
 	var err error
-	ring, err = keyring.Open(appName)
-	cobra.CheckErr(err)
+	ring, err = keyring.Open(appName, keyring.Options{
+		Backend:    os.Getenv("BULUT_KEYRING"),
+		FilePath:   os.Getenv("BULUT_KEYRING_FILE"),
+		Passphrase: askKeyringPassphrase,
+	})
+	checkErr(err)
+}
+
+func askKeyringPassphrase(create bool) (string, error) {
+	if passphrase := os.Getenv("BULUT_KEYRING_PASSPHRASE"); passphrase != "" {
+		return passphrase, nil
+	}
+	message := "Enter the passphrase of the credential file"
+	if create {
+		message = "No OS keyring found, choose a passphrase to encrypt the credential file"
+	}
+	var passphrase string
+	err := ask(&survey.Password{Message: message}, &passphrase, "set BULUT_KEYRING_PASSPHRASE or BULUT_TOKEN", survey.WithValidator(survey.Required))
+	if err != nil {
+		var cliErr *cliError
+		if errors.As(err, &cliErr) {
+			return "", err
+		}
+		return "", errors.New("credential file is locked, set BULUT_KEYRING_PASSPHRASE or BULUT_TOKEN: " + err.Error())
+	}
+	return passphrase, nil
 }
 
 func checkConfigAndUpdateDir(currentDir string) (bool, string) {
@@ -120,6 +176,6 @@ func checkConfigAndUpdateDir(currentDir string) (bool, string) {
 
 func updateDir(currentDir string) string {
 	updatedDir, err := filepath.Abs(filepath.Join(currentDir, ".."))
-	cobra.CheckErr(err)
+	checkErr(err)
 	return updatedDir
 }
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"strconv"
+)
+
+var scaleCmd = &cobra.Command{
+	Use:   "scale <replicas>",
+	Short: "Set the number of replicas of the deployment",
+	Args:  cobra.ExactArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return checkLogin()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return scale(cmd, args)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(scaleCmd)
+	scaleCmd.Flags().String("balancer", "", "Load balancing strategy: round-robin or least-connections")
+}
+
+type scaleResponse struct {
+	Namespace  string `json:"namespace"`
+	Deployment string `json:"deployment"`
+	Replicas   int    `json:"replicas"`
+	Balancer   string `json:"balancer"`
+}
+
+func scale(cmd *cobra.Command, args []string) error {
+	replicas, err := strconv.Atoi(args[0])
+	if err != nil || replicas < 1 {
+		return newError(codeInvalidArgument, "replicas must be a positive number")
+	}
+	balancer, err := cmd.Flags().GetString("balancer")
+	if err != nil {
+		return err
+	}
+
+	namespace, deploymentName, err := getConfiguredDeployment()
+	if err != nil {
+		return err
+	}
+
+	progressf("Scaling %s/%s to %d replicas\n", namespace, deploymentName, replicas)
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+	scaled, err := c.Scale(commandContext(), namespace, deploymentName, replicas, balancer)
+	if err != nil {
+		return err
+	}
+	resp := scaleResponse{
+		Namespace:  namespace,
+		Deployment: deploymentName,
+		Replicas:   scaled.Replicas,
+		Balancer:   scaled.Balancer,
+	}
+	return printResult(resp, func() error {
+		fmt.Printf("Deployment is running %d replicas (%s)\n", resp.Replicas, resp.Balancer)
+		return nil
+	})
+}
@@ -0,0 +1,228 @@
+package cmd
+
+import (
+	"bulut-cli/pkg/client"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Sessions are refreshed when they expire in less than this
+const sessionRefreshMargin = 5 * time.Minute
+
+type oidcDiscovery struct {
+	DeviceAuthorizationEndpoint string `json:"device_authorization_endpoint"`
+	TokenEndpoint               string `json:"token_endpoint"`
+}
+
+type deviceAuthorization struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+type deviceTokenResponse struct {
+	IDToken string `json:"id_token"`
+	Error   string `json:"error"`
+}
+
+// loginSSO runs the OAuth device authorization flow against the identity
+// provider of the server and exchanges the resulting ID token for a session.
+func loginSSO(serverURL string) error {
+	c, err := newClientFor(serverURL)
+	if err != nil {
+		return err
+	}
+	config, err := c.OIDCConfig(commandContext())
+	if err != nil {
+		return fmt.Errorf("failed to get SSO configuration of the server: %w", err)
+	}
+	var discovery oidcDiscovery
+	err = getJSON(strings.TrimSuffix(config.Issuer, "/")+"/.well-known/openid-configuration", &discovery)
+	if err != nil {
+		return fmt.Errorf("failed to discover identity provider: %w", err)
+	}
+	if discovery.DeviceAuthorizationEndpoint == "" {
+		return errors.New("identity provider does not support the device authorization flow")
+	}
+
+	var device deviceAuthorization
+	err = postForm(discovery.DeviceAuthorizationEndpoint, url.Values{
+		"client_id": {config.ClientID},
+		"scope":     {strings.Join(config.Scopes, " ")},
+	}, &device)
+	if err != nil {
+		return fmt.Errorf("failed to start device authorization: %w", err)
+	}
+
+	verificationURI := device.VerificationURI
+	if device.VerificationURIComplete != "" {
+		verificationURI = device.VerificationURIComplete
+	}
+	progressf("Open the following URL in a browser to log in:\n")
+	progressf("  %s\n", verificationURI)
+	progressf("and confirm the code: %s\n", device.UserCode)
+
+	idToken, err := pollDeviceToken(discovery.TokenEndpoint, config.ClientID, device)
+	if err != nil {
+		return err
+	}
+
+	session, err := c.LoginOIDC(commandContext(), idToken)
+	if err != nil {
+		return fmt.Errorf("failed to log in to the server: %w", err)
+	}
+	if err := saveSession(serverURL, session); err != nil {
+		return err
+	}
+	result := loginResult{
+		Server:    serverURL,
+		User:      session.User.Name,
+		Scopes:    session.Scopes,
+		ExpiresAt: &session.ExpiresAt,
+	}
+	return printResult(result, func() error {
+		fmt.Printf("Logged in as %s (%s), session expires at %s\n", session.User.Name, session.Scopes, session.ExpiresAt.Local().Format(time.RFC822))
+		return nil
+	})
+}
+
+func pollDeviceToken(tokenEndpoint, clientID string, device deviceAuthorization) (string, error) {
+	interval := time.Duration(device.Interval) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	deadline := time.Now().Add(time.Duration(device.ExpiresIn) * time.Second)
+	if device.ExpiresIn <= 0 {
+		deadline = time.Now().Add(10 * time.Minute)
+	}
+
+	for time.Now().Before(deadline) {
+		time.Sleep(interval)
+
+		resp, err := http.PostForm(tokenEndpoint, url.Values{
+			"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+			"device_code": {device.DeviceCode},
+			"client_id":   {clientID},
+		})
+		if err != nil {
+			return "", err
+		}
+		var token deviceTokenResponse
+		err = json.NewDecoder(resp.Body).Decode(&token)
+		resp.Body.Close()
+		if err != nil {
+			return "", fmt.Errorf("invalid token response: %w", err)
+		}
+
+		switch token.Error {
+		case "":
+			if token.IDToken == "" {
+				return "", errors.New("identity provider did not return an ID token")
+			}
+			return token.IDToken, nil
+		case "authorization_pending":
+		case "slow_down":
+			interval += 5 * time.Second
+		case "access_denied":
+			return "", errors.New("login was denied")
+		case "expired_token":
+			return "", errors.New("login code expired, try again")
+		default:
+			return "", fmt.Errorf("login failed: %s", token.Error)
+		}
+	}
+	return "", errors.New("login code expired, try again")
+}
+
+func saveSession(serverURL string, session *client.Session) error {
+	if err := saveToken(serverURL, session.Token); err != nil {
+		return err
+	}
+	if err := ring.Set(fmt.Sprintf("refresh-token_%s", serverURL), session.RefreshToken); err != nil {
+		return err
+	}
+	return ring.Set(fmt.Sprintf("token-expires-at_%s", serverURL), session.ExpiresAt.Format(time.RFC3339))
+}
+
+// refreshSessionIfNeeded renews an SSO session that is about to expire. Keys
+// saved without an expiry, like API keys, are left as they are.
+func refreshSessionIfNeeded(serverURL string) error {
+	expiresAtStr, err := ring.Get(fmt.Sprintf("token-expires-at_%s", serverURL))
+	if err != nil {
+		if errors.Is(err, ring.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+	expiresAt, err := time.Parse(time.RFC3339, expiresAtStr)
+	if err == nil && time.Until(expiresAt) > sessionRefreshMargin {
+		return nil
+	}
+
+	refreshToken, err := ring.Get(fmt.Sprintf("refresh-token_%s", serverURL))
+	if err != nil {
+		if errors.Is(err, ring.ErrNotFound) {
+			return newError(codeNotLoggedIn, "your session on %s expired, run `bulut login --sso` again", serverURL)
+		}
+		return err
+	}
+	c, err := newClientFor(serverURL)
+	if err != nil {
+		return err
+	}
+	session, err := c.RefreshSession(commandContext(), refreshToken)
+	if err != nil {
+		return &cliError{
+			Code:    codeNotLoggedIn,
+			Message: fmt.Sprintf("your session on %s expired, run `bulut login --sso` again: %v", serverURL, err),
+			err:     err,
+		}
+	}
+	return saveSession(serverURL, session)
+}
+
+// deleteSession removes the refresh token and expiry saved by an SSO login.
+func deleteSession(serverURL string) {
+	_ = ring.Delete(fmt.Sprintf("refresh-token_%s", serverURL))
+	_ = ring.Delete(fmt.Sprintf("token-expires-at_%s", serverURL))
+}
+
+// getJSON and postForm talk to the identity provider, the server is called
+// with the client.
+func getJSON(url string, out interface{}) error {
+	resp, err := http.Get(url)
+	if err != nil {
+		return err
+	}
+	return decodeResponse(resp, out)
+}
+
+func postForm(url string, values url.Values, out interface{}) error {
+	resp, err := http.PostForm(url, values)
+	if err != nil {
+		return err
+	}
+	return decodeResponse(resp, out)
+}
+
+func decodeResponse(resp *http.Response, out interface{}) error {
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return &cliError{
+			Code:    statusCode(resp.StatusCode),
+			Message: strings.TrimSpace(fmt.Sprintf("%s %s", resp.Status, body)),
+			Status:  resp.StatusCode,
+		}
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"time"
+)
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the status of the deployment",
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return checkLogin()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return status(args)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+}
+
+type statusResult struct {
+	Namespace         string     `json:"namespace"`
+	Deployment        string     `json:"deployment"`
+	Replicas          int        `json:"replicas"`
+	Balancer          string     `json:"balancer"`
+	RestartPolicy     string     `json:"restart_policy"`
+	RestartMaxRetries int        `json:"restart_max_retries"`
+	RestartCount      int        `json:"restart_count"`
+	LastExitCode      int        `json:"last_exit_code"`
+	LastExitReason    string     `json:"last_exit_reason"`
+	LastExitAt        *time.Time `json:"last_exit_at"`
+	CrashLooping      bool       `json:"crash_looping"`
+}
+
+func status(args []string) error {
+	namespace, deploymentName, err := getConfiguredDeployment()
+	if err != nil {
+		return err
+	}
+
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+	dep, err := c.GetDeployment(commandContext(), namespace, deploymentName)
+	if err != nil {
+		return err
+	}
+
+	result := statusResult{
+		Namespace:         namespace,
+		Deployment:        dep.Name,
+		Replicas:          dep.Replicas,
+		Balancer:          dep.Balancer,
+		RestartPolicy:     dep.RestartPolicy,
+		RestartMaxRetries: dep.RestartMaxRetries,
+		RestartCount:      dep.RestartCount,
+		LastExitCode:      dep.LastExitCode,
+		LastExitReason:    dep.LastExitReason,
+		LastExitAt:        dep.LastExitAt,
+		CrashLooping:      dep.CrashLooping,
+	}
+	return printResult(result, func() error {
+		fmt.Printf("Deployment:     %s/%s\n", namespace, dep.Name)
+		fmt.Printf("Replicas:       %d (%s)\n", dep.Replicas, dep.Balancer)
+		restartPolicy := dep.RestartPolicy
+		if restartPolicy == "on-failure" {
+			restartPolicy = fmt.Sprintf("%s (max %d retries)", restartPolicy, dep.RestartMaxRetries)
+		}
+		fmt.Printf("Restart policy: %s\n", restartPolicy)
+		fmt.Printf("Restarts:       %d\n", dep.RestartCount)
+		if dep.LastExitAt != nil {
+			fmt.Printf("Last exit:      code %d (%s) at %s\n", dep.LastExitCode, dep.LastExitReason, dep.LastExitAt.Local().Format(time.RFC1123))
+		}
+		if dep.CrashLooping {
+			fmt.Println("Warning: the deployment is crash-looping, restarts are backed off")
+		}
+		return nil
+	})
+}
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"bulut-cli/pkg/client"
+	"fmt"
+	"github.com/spf13/cobra"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+var tokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "Manage API tokens (requires the admin scope)",
+	Long: `API tokens give users access to the Bulut server with a set of scopes:
+read, deploy (includes read) or admin (includes deploy).`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return checkLogin()
+	},
+}
+
+var tokenCreateCmd = &cobra.Command{
+	Use:   "create <user>",
+	Short: "Create a token for a user, creating the user if needed",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return createToken(cmd, args)
+	},
+}
+
+var tokenListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List tokens",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return listTokens(cmd, args)
+	},
+}
+
+var tokenRevokeCmd = &cobra.Command{
+	Use:   "revoke <token id>",
+	Short: "Revoke a token",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return revokeToken(args)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(tokenCmd)
+	tokenCmd.AddCommand(tokenCreateCmd)
+	tokenCmd.AddCommand(tokenListCmd)
+	tokenCmd.AddCommand(tokenRevokeCmd)
+
+	tokenCreateCmd.Flags().String("name", "", "Name of the token, to tell tokens apart")
+	tokenCreateCmd.Flags().String("scopes", "deploy", "Comma separated scopes: read, deploy, admin")
+	tokenCreateCmd.Flags().String("expires-in", "", "Lifetime of the token, for example 720h (default never expires)")
+	tokenListCmd.Flags().String("user", "", "Only list tokens of this user")
+}
+
+func createToken(cmd *cobra.Command, args []string) error {
+	name, _ := cmd.Flags().GetString("name")
+	scopes, _ := cmd.Flags().GetString("scopes")
+	expiresIn, _ := cmd.Flags().GetString("expires-in")
+
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+	resp, err := c.CreateToken(commandContext(), client.CreateTokenRequest{
+		User:      args[0],
+		Name:      name,
+		Scopes:    scopes,
+		ExpiresIn: expiresIn,
+	})
+	if err != nil {
+		return err
+	}
+
+	return printResult(resp, func() error {
+		fmt.Printf("Created token %s for %s with scopes %s\n", resp.Details.ID, resp.Details.User.Name, resp.Details.Scopes)
+		fmt.Println("Save it now, it will not be shown again:")
+		fmt.Println(resp.Token)
+		return nil
+	})
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+func listTokens(cmd *cobra.Command, args []string) error {
+	user, _ := cmd.Flags().GetString("user")
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+	tokens, err := c.ListTokens(commandContext(), user)
+	if err != nil {
+		return err
+	}
+
+	return printResult(tokens, func() error {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tUSER\tNAME\tPREFIX\tSCOPES\tEXPIRES\tREVOKED\tLAST USED")
+		for _, t := range tokens {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", t.ID, t.User.Name, t.Name, t.Prefix, t.Scopes,
+				formatTime(t.ExpiresAt), formatTime(t.RevokedAt), formatTime(t.LastUsedAt))
+		}
+		return w.Flush()
+	})
+}
+
+func revokeToken(args []string) error {
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+	if err := c.RevokeToken(commandContext(), args[0]); err != nil {
+		return err
+	}
+	return printResult(map[string]string{"id": args[0]}, func() error {
+		fmt.Printf("Token %s revoked\n", args[0])
+		return nil
+	})
+}
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"context"
+	"crypto/rand"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"os"
+	"sync"
+)
+
+var (
+	traceOnce sync.Once
+	traceCtx  context.Context
+)
+
+// commandContext returns the context of the requests of the command. They all
+// carry the same trace, so the spans the server records for them are grouped.
+// The trace in the TRACEPARENT environment variable is continued, like the
+// one of a CI pipeline, otherwise a new one is started.
+func commandContext() context.Context {
+	traceOnce.Do(func() {
+		traceCtx = newTraceContext()
+	})
+	return traceCtx
+}
+
+func newTraceContext() context.Context {
+	ctx := propagation.TraceContext{}.Extract(context.Background(), propagation.MapCarrier{
+		"traceparent": os.Getenv("TRACEPARENT"),
+		"tracestate":  os.Getenv("TRACESTATE"),
+	})
+	if trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx
+	}
+
+	var config trace.SpanContextConfig
+	if _, err := rand.Read(config.TraceID[:]); err != nil {
+		return context.Background()
+	}
+	if _, err := rand.Read(config.SpanID[:]); err != nil {
+		return context.Background()
+	}
+	config.TraceFlags = trace.FlagsSampled
+	return trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(config))
+}
@@ -1,6 +1,8 @@
 package cmd
 
 import (
+	"bulut-cli/pkg/client"
+	"errors"
 	"fmt"
 	"github.com/spf13/cobra"
 )
@@ -10,14 +12,69 @@ var (
 	version string = "?.?.?"
 )
 
+var versionClientOnly bool
+
 var versionCmd = &cobra.Command{
 	Use:   "version",
-	Short: "Show the version of the CLI app",
+	Short: "Show the version of the CLI app and of the server",
 	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Printf("Bulut version: %s\n", version)
+		result := versionResult{Version: version, APIVersion: client.APIVersion}
+		if !versionClientOnly {
+			var compatible bool
+			result.Server, compatible, result.ServerError = serverVersion()
+			if result.Server != nil {
+				result.Compatible = &compatible
+			}
+		}
+
+		_ = printResult(result, func() error {
+			fmt.Printf("Bulut version: %s (API v%d)\n", version, client.APIVersion)
+			switch {
+			case versionClientOnly:
+			case result.Server == nil:
+				fmt.Printf("Server version: unknown, %s\n", result.ServerError)
+			case result.Server.Legacy:
+				fmt.Println("Server version: unknown, the server is from before API versioning")
+			default:
+				fmt.Printf("Server version: %s (API v%d to v%d)\n", result.Server.Version, result.Server.API.Min, result.Server.API.Max)
+				if !*result.Compatible {
+					fmt.Println("Warning: the server does not serve the API version of the CLI")
+				}
+			}
+			return nil
+		})
 	},
 }
 
+type versionResult struct {
+	Version    string                `json:"version"`
+	APIVersion int                   `json:"api_version"`
+	Server     *client.ServerVersion `json:"server,omitempty"`
+	// Whether the server serves the API version of the CLI, nil without server
+	Compatible  *bool  `json:"compatible,omitempty"`
+	ServerError string `json:"server_error,omitempty"`
+}
+
+// serverVersion asks the current server for its version, without refusing
+// incompatible servers like the other commands do.
+func serverVersion() (*client.ServerVersion, bool, string) {
+	c, err := client.New(client.Options{BaseURL: getServerURL(), UserAgent: "bulut-cli/" + version})
+	if err != nil {
+		return nil, false, err.Error()
+	}
+	server, err := c.Negotiate(commandContext())
+	var incompatible *client.IncompatibleError
+	if errors.As(err, &incompatible) {
+		return server, false, ""
+	}
+	if err != nil {
+		return nil, false, err.Error()
+	}
+	return server, true, ""
+}
+
 func init() {
 	rootCmd.AddCommand(versionCmd)
+
+	versionCmd.Flags().BoolVar(&versionClientOnly, "client", false, "Only show the version of the CLI")
 }
@@ -0,0 +1,275 @@
+package cmd
+
+import (
+	"bulut-cli/pkg/client"
+	"fmt"
+	"github.com/spf13/cobra"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+var volumesCmd = &cobra.Command{
+	Use:     "volumes",
+	Aliases: []string{"volume", "vol"},
+	Short:   "Manage the volumes of the deployment and their snapshots",
+	Long: `Manage the volumes mounted into the replicas of a deployment and their
+snapshots. Commands act on the deployment of the config unless --deployment
+is given.`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return checkLogin()
+	},
+}
+
+var volumesListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List the volumes of the deployment",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return listVolumes(cmd)
+	},
+}
+
+var volumesSetCmd = &cobra.Command{
+	Use:   "set [name:/path]...",
+	Short: "Set the volumes mounted into the replicas",
+	Long: `Set the volumes mounted into the replicas of the deployment, replacing
+the current ones. Running replicas are replaced by ones with the new volumes.
+Volumes left out are no longer mounted but keep their data until the
+deployment is deleted with --volumes. Without arguments all volumes are
+unmounted.`,
+	Example: "  bulut volumes set data:/app/data uploads:/app/public/uploads",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setVolumes(cmd, args)
+	},
+}
+
+var volumesSnapshotCmd = &cobra.Command{
+	Use:   "snapshot <volume>",
+	Short: "Take a snapshot of a volume",
+	Long: `Take a snapshot of the content of a volume while the deployment keeps
+running. The server keeps a limited number of snapshots per volume and
+removes the oldest ones.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return snapshotVolume(cmd, args)
+	},
+}
+
+var volumesSnapshotsCmd = &cobra.Command{
+	Use:   "snapshots [volume]",
+	Short: "List the snapshots of the volumes, newest first",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return listSnapshots(cmd, args)
+	},
+}
+
+var volumesRestoreCmd = &cobra.Command{
+	Use:   "restore <snapshot>",
+	Short: "Replace the content of a volume with a snapshot",
+	Long: `Replace the content of a volume with a snapshot. The replicas of the
+deployment are stopped during the restore and started again afterwards. The
+snapshot is restored into its own volume, or into the volume given with --to,
+which is created if it does not exist.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return restoreSnapshot(cmd, args)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(volumesCmd)
+	volumesCmd.AddCommand(volumesListCmd)
+	volumesCmd.AddCommand(volumesSetCmd)
+	volumesCmd.AddCommand(volumesSnapshotCmd)
+	volumesCmd.AddCommand(volumesSnapshotsCmd)
+	volumesCmd.AddCommand(volumesRestoreCmd)
+
+	volumesCmd.PersistentFlags().StringP("deployment", "d", "", "Deployment as namespace/deployment, the one of the config by default")
+	volumesSetCmd.Flags().BoolP("yes", "y", false, "Do not ask for confirmation")
+	volumesRestoreCmd.Flags().String("to", "", "Volume to restore into, the volume of the snapshot by default")
+	volumesRestoreCmd.Flags().BoolP("yes", "y", false, "Do not ask for confirmation")
+}
+
+// volumesDeployment returns the deployment of --deployment, or the configured
+// one.
+func volumesDeployment(cmd *cobra.Command) (string, string, error) {
+	var args []string
+	if deployment, _ := cmd.Flags().GetString("deployment"); deployment != "" {
+		args = append(args, deployment)
+	}
+	return parseDeploymentArg(args)
+}
+
+type volumesResult struct {
+	Namespace  string          `json:"namespace"`
+	Deployment string          `json:"deployment"`
+	Volumes    []client.Volume `json:"volumes"`
+}
+
+func listVolumes(cmd *cobra.Command) error {
+	namespace, deploymentName, err := volumesDeployment(cmd)
+	if err != nil {
+		return err
+	}
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+	volumes, err := c.Volumes(commandContext(), namespace, deploymentName)
+	if err != nil {
+		return err
+	}
+	result := volumesResult{Namespace: namespace, Deployment: deploymentName, Volumes: volumes}
+	return printResult(result, func() error {
+		if len(volumes) == 0 {
+			fmt.Printf("Deployment %s/%s has no volumes\n", namespace, deploymentName)
+			return nil
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tPATH")
+		for _, volume := range volumes {
+			fmt.Fprintf(w, "%s\t%s\n", volume.Name, volume.Path)
+		}
+		return w.Flush()
+	})
+}
+
+func setVolumes(cmd *cobra.Command, args []string) error {
+	volumes := make([]client.Volume, 0, len(args))
+	for _, arg := range args {
+		name, path, ok := strings.Cut(arg, ":")
+		if !ok || name == "" || !strings.HasPrefix(path, "/") {
+			return newError(codeInvalidArgument, "volume %q must be given as name:/path", arg)
+		}
+		volumes = append(volumes, client.Volume{Name: name, Path: path})
+	}
+	namespace, deploymentName, err := volumesDeployment(cmd)
+	if err != nil {
+		return err
+	}
+	if len(volumes) == 0 {
+		message := fmt.Sprintf("Unmount all volumes of %s/%s? Their data is kept.", namespace, deploymentName)
+		if err := confirm(cmd, message); err != nil {
+			return err
+		}
+	}
+
+	progressf("Setting the volumes of %s/%s\n", namespace, deploymentName)
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+	volumes, err = c.SetVolumes(commandContext(), namespace, deploymentName, volumes)
+	if err != nil {
+		return err
+	}
+	result := volumesResult{Namespace: namespace, Deployment: deploymentName, Volumes: volumes}
+	return printResult(result, func() error {
+		fmt.Printf("Deployment %s/%s has %d volumes\n", namespace, deploymentName, len(volumes))
+		return nil
+	})
+}
+
+func snapshotVolume(cmd *cobra.Command, args []string) error {
+	namespace, deploymentName, err := volumesDeployment(cmd)
+	if err != nil {
+		return err
+	}
+
+	progressf("Taking a snapshot of volume %s of %s/%s\n", args[0], namespace, deploymentName)
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+	snapshot, err := c.CreateSnapshot(commandContext(), namespace, deploymentName, args[0])
+	if err != nil {
+		return err
+	}
+	return printResult(snapshot, func() error {
+		fmt.Printf("Snapshot %s taken (%s)\n", snapshot.ID, formatSize(snapshot.SizeBytes))
+		return nil
+	})
+}
+
+type snapshotsResult struct {
+	Namespace  string            `json:"namespace"`
+	Deployment string            `json:"deployment"`
+	Snapshots  []client.Snapshot `json:"snapshots"`
+}
+
+func listSnapshots(cmd *cobra.Command, args []string) error {
+	namespace, deploymentName, err := volumesDeployment(cmd)
+	if err != nil {
+		return err
+	}
+	var volume string
+	if len(args) > 0 {
+		volume = args[0]
+	}
+
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+	snapshots, err := c.Snapshots(commandContext(), namespace, deploymentName, volume)
+	if err != nil {
+		return err
+	}
+	result := snapshotsResult{Namespace: namespace, Deployment: deploymentName, Snapshots: snapshots}
+	return printResult(result, func() error {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tVOLUME\tSIZE\tCREATED")
+		for _, snapshot := range snapshots {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", snapshot.ID, snapshot.Volume, formatSize(snapshot.SizeBytes), formatTime(&snapshot.CreatedAt))
+		}
+		return w.Flush()
+	})
+}
+
+func restoreSnapshot(cmd *cobra.Command, args []string) error {
+	namespace, deploymentName, err := volumesDeployment(cmd)
+	if err != nil {
+		return err
+	}
+	target, _ := cmd.Flags().GetString("to")
+
+	message := fmt.Sprintf("Stop %s/%s and replace the content of the volume of the snapshot with %s?", namespace, deploymentName, args[0])
+	if target != "" {
+		message = fmt.Sprintf("Stop %s/%s and replace the content of volume %s with %s?", namespace, deploymentName, target, args[0])
+	}
+	if err := confirm(cmd, message); err != nil {
+		return err
+	}
+
+	progressf("Restoring %s\n", args[0])
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+	restored, err := c.RestoreSnapshot(commandContext(), namespace, deploymentName, args[0], target)
+	if err != nil {
+		return err
+	}
+	return printResult(restored, func() error {
+		fmt.Printf("Snapshot %s restored into volume %s\n", restored.Snapshot, restored.Volume)
+		return nil
+	})
+}
+
+// formatSize formats a number of bytes with a binary unit, like 1.5 MiB.
+func formatSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
@@ -16,40 +16,69 @@ var whoamiCmd = &cobra.Command{
 	Use:   "whoami",
 	Short: "Show the login info saved by the login command",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return whoami(args)
+		return whoami(cmd)
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(whoamiCmd)
+	whoamiCmd.Flags().Bool("show-token", false, "Show the API key or token without asking")
 }
 
-func whoami(args []string) error {
-	serverURL := getServerURL()
-	apiKey, err := getApiKeyForServer(serverURL)
+type whoamiResult struct {
+	Context   string `json:"context,omitempty"`
+	Server    string `json:"server"`
+	Namespace string `json:"namespace,omitempty"`
+	LoggedIn  bool   `json:"logged_in"`
+	Token     string `json:"token,omitempty"`
+}
+
+func whoami(cmd *cobra.Command) error {
+	contextName, _, err := getCurrentContext()
 	if err != nil {
-		if errors.Is(err, ring.ErrNotFound) {
-			fmt.Println("You are not logged in!")
-			return nil
-		}
 		return err
 	}
-	if apiKey == "" {
-		fmt.Println("You are not logged in! (empty API key)")
-		return nil
+	result := whoamiResult{
+		Context:   contextName,
+		Server:    getServerURL(),
+		Namespace: getDefaultNamespace(),
 	}
-	var confirmation bool
-	prompt := &survey.Confirm{
-		Message: "Are you sure you want to expose your API key?",
-	}
-	err = survey.AskOne(prompt, &confirmation)
-	if err != nil {
+	apiKey, err := getApiKeyForServer(result.Server)
+	if err != nil && !errors.Is(err, ring.ErrNotFound) {
 		return err
 	}
-	if confirmation != true {
-		fmt.Println("Aborting...")
-		return nil
+	result.LoggedIn = apiKey != ""
+
+	if result.LoggedIn {
+		showToken, _ := cmd.Flags().GetBool("show-token")
+		// The token is only asked about when the user is there to answer
+		if !showToken && !structuredOutput() && !nonInteractive() {
+			err = ask(&survey.Confirm{
+				Message: "Are you sure you want to expose your API key?",
+			}, &showToken, "pass --show-token")
+			if err != nil {
+				return err
+			}
+		}
+		if showToken {
+			result.Token = apiKey
+		}
 	}
-	fmt.Println("API Key:", apiKey)
-	return nil
+
+	return printResult(result, func() error {
+		if result.Context != "" {
+			fmt.Printf("Context: %s (%s)\n", result.Context, result.Server)
+		} else {
+			fmt.Println("Server:", result.Server)
+		}
+		if result.Namespace != "" {
+			fmt.Println("Namespace:", result.Namespace)
+		}
+		if !result.LoggedIn {
+			fmt.Println("You are not logged in!")
+		} else if result.Token != "" {
+			fmt.Println("API Key:", result.Token)
+		}
+		return nil
+	})
 }
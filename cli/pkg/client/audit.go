@@ -0,0 +1,63 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+type AuditEntry struct {
+	ID         string    `json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	Actor      string    `json:"actor"`
+	UserID     *string   `json:"user_id"`
+	TokenID    *string   `json:"token_id"`
+	Namespace  string    `json:"namespace"`
+	Deployment string    `json:"deployment"`
+	// Like deployment.upload
+	Action string `json:"action"`
+	// Summary of the query and body of the request
+	Payload string `json:"payload"`
+	Status  int    `json:"status"`
+	// success or failure
+	Result     string  `json:"result"`
+	Error      string  `json:"error,omitempty"`
+	SourceIP   string  `json:"source_ip"`
+	RevisionID *string `json:"revision_id,omitempty"`
+}
+
+type ListAuditOptions struct {
+	ListOptions
+	Actor      string
+	Namespace  string
+	Deployment string
+	Action     string
+	// Durations like 24h or RFC3339 times
+	Since string
+	Until string
+}
+
+type AuditList struct {
+	Entries []AuditEntry `json:"entries"`
+	Pagination
+}
+
+// ListAudit lists audit entries, newest first. It requires the admin scope.
+func (c *Client) ListAudit(ctx context.Context, opts ListAuditOptions) (*AuditList, error) {
+	query := opts.query()
+	for key, value := range map[string]string{
+		"actor":      opts.Actor,
+		"namespace":  opts.Namespace,
+		"deployment": opts.Deployment,
+		"action":     opts.Action,
+		"since":      opts.Since,
+		"until":      opts.Until,
+	} {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	var list AuditList
+	_, err := c.do(ctx, request{method: http.MethodGet, path: "/audit", query: query, auth: true}, &list)
+	return &list, err
+}
@@ -0,0 +1,56 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// OIDCConfig tells where to run the device authorization flow of an SSO
+// login.
+type OIDCConfig struct {
+	Issuer   string   `json:"issuer"`
+	ClientID string   `json:"client_id"`
+	Scopes   []string `json:"scopes"`
+}
+
+// Session is a short-lived token with a refresh token to renew it.
+type Session struct {
+	Token            string    `json:"token"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+	User             User      `json:"user"`
+	Scopes           string    `json:"scopes"`
+}
+
+// OIDCConfig returns the SSO configuration of the server, or a not_configured
+// error if SSO login is not set up.
+func (c *Client) OIDCConfig(ctx context.Context) (*OIDCConfig, error) {
+	var config OIDCConfig
+	_, err := c.do(ctx, request{method: http.MethodGet, path: "/auth/oidc"}, &config)
+	return &config, err
+}
+
+// LoginOIDC exchanges an ID token of the issuer for a session.
+func (c *Client) LoginOIDC(ctx context.Context, idToken string) (*Session, error) {
+	var session Session
+	_, err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/auth/oidc/token",
+		body:   map[string]string{"id_token": idToken},
+	}, &session)
+	return &session, err
+}
+
+// RefreshSession exchanges a refresh token for a new session. The refresh
+// token can only be used once.
+func (c *Client) RefreshSession(ctx context.Context, refreshToken string) (*Session, error) {
+	var session Session
+	_, err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/auth/refresh",
+		body:   map[string]string{"refresh_token": refreshToken},
+	}, &session)
+	return &session, err
+}
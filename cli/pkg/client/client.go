@@ -0,0 +1,259 @@
+// Package client is the Go client of the Bulut server API, used by the CLI
+// and usable by other tools.
+//
+// Errors returned by the server are *Error values with the stable code of the
+// server. Idempotent requests are retried on connection errors and on
+// responses telling to try again later. Requests carry the OpenTelemetry trace
+// of their context in a traceparent header.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go.opentelemetry.io/otel/propagation"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Version of the client package, sent in the User-Agent header. It follows
+// semantic versioning, breaking changes bump the major version.
+const Version = "1.0.0"
+
+const (
+	defaultTimeout    = 30 * time.Second
+	defaultMaxRetries = 3
+	retryBaseDelay    = 500 * time.Millisecond
+)
+
+type Options struct {
+	// URL of the server, like https://bulut.example.com
+	BaseURL string
+	// API key or token sent in the authorization header
+	Token string
+	// Called before every request for the token instead of Token, to renew
+	// sessions that are about to expire
+	TokenFunc func() (string, error)
+	// Used for the requests, http.DefaultClient if nil
+	HTTPClient *http.Client
+	// Limit of a request including retries, 30s if zero. Log streams and
+	// uploads are only limited by their context.
+	Timeout time.Duration
+	// Retries of idempotent requests, 3 if zero and none if negative
+	MaxRetries int
+	// Prepended to the User-Agent header, like "bulut-cli/1.2.0"
+	UserAgent string
+}
+
+type Client struct {
+	baseURL    string
+	token      func() (string, error)
+	httpClient *http.Client
+	timeout    time.Duration
+	maxRetries int
+	userAgent  string
+	// Prepended to the paths of the API, empty for servers from before
+	// versioning
+	apiPrefix string
+}
+
+func New(opts Options) (*Client, error) {
+	u, err := url.Parse(opts.BaseURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid server URL: %s", opts.BaseURL)
+	}
+
+	c := &Client{
+		baseURL:    strings.TrimSuffix(opts.BaseURL, "/"),
+		httpClient: opts.HTTPClient,
+		timeout:    opts.Timeout,
+		maxRetries: opts.MaxRetries,
+		userAgent:  "bulut-go-client/" + Version,
+		apiPrefix:  fmt.Sprintf("/v%d", APIVersion),
+	}
+	if c.httpClient == nil {
+		c.httpClient = http.DefaultClient
+	}
+	if c.timeout == 0 {
+		c.timeout = defaultTimeout
+	}
+	if c.maxRetries == 0 {
+		c.maxRetries = defaultMaxRetries
+	} else if c.maxRetries < 0 {
+		c.maxRetries = 0
+	}
+	if opts.UserAgent != "" {
+		c.userAgent = opts.UserAgent + " " + c.userAgent
+	}
+	c.token = opts.TokenFunc
+	if c.token == nil {
+		token := opts.Token
+		c.token = func() (string, error) { return token, nil }
+	}
+	return c, nil
+}
+
+// BaseURL returns the URL of the server the client talks to.
+func (c *Client) BaseURL() string {
+	return c.baseURL
+}
+
+// request describes an API call for do.
+type request struct {
+	method string
+	path   string
+	query  url.Values
+	// Encoded as JSON
+	body interface{}
+	// Streamed as is with contentType instead of body, requests with it are
+	// never retried as it can only be read once
+	rawBody     io.Reader
+	contentType string
+	// Requests without it are sent without the authorization header
+	auth bool
+	// Not limited by the timeout, for uploads
+	noTimeout bool
+	// Never retried, for requests with side effects despite their method
+	noRetry bool
+	// Streams are not limited by the timeout and their body is left open
+	stream bool
+	// Paths outside of the API, like /version, are not prefixed
+	unversioned bool
+	// Sent as X-Request-ID, the server assigns one if empty
+	requestID string
+}
+
+// do sends the request, retrying idempotent ones, and decodes the response
+// into out if it is not nil. For streams the response is returned with an open
+// body, which the caller must close.
+func (c *Client) do(ctx context.Context, req request, out interface{}) (*http.Response, error) {
+	if !req.stream && !req.noTimeout {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	var body []byte
+	contentType := req.contentType
+	if req.body != nil {
+		data, err := json.Marshal(req.body)
+		if err != nil {
+			return nil, err
+		}
+		body = data
+		contentType = "application/json"
+	}
+	target := c.baseURL + req.path
+	if !req.unversioned {
+		target = c.baseURL + c.apiPrefix + req.path
+	}
+	if len(req.query) > 0 {
+		target += "?" + req.query.Encode()
+	}
+
+	retries := 0
+	if idempotent(req.method) && !req.noRetry && req.rawBody == nil {
+		retries = c.maxRetries
+	}
+	for attempt := 0; ; attempt++ {
+		reader := req.rawBody
+		if body != nil {
+			reader = bytes.NewReader(body)
+		}
+		resp, err := c.send(ctx, req, target, reader, contentType)
+		if attempt < retries && retryable(resp, err) {
+			if resp != nil {
+				resp.Body.Close()
+			}
+			if err := sleep(ctx, retryDelay(attempt)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			defer resp.Body.Close()
+			return nil, decodeError(resp)
+		}
+		if req.stream {
+			return resp, nil
+		}
+		defer resp.Body.Close()
+		if out != nil {
+			if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+				return nil, fmt.Errorf("invalid response from the server: %w", err)
+			}
+		}
+		return resp, nil
+	}
+}
+
+func (c *Client) send(ctx context.Context, req request, target string, body io.Reader, contentType string) (*http.Response, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("User-Agent", c.userAgent)
+	httpReq.Header.Set("Accept", "application/json")
+	// The server continues the trace of ctx, if it has one
+	propagation.TraceContext{}.Inject(ctx, propagation.HeaderCarrier(httpReq.Header))
+	if contentType != "" {
+		httpReq.Header.Set("Content-Type", contentType)
+	}
+	if req.requestID != "" {
+		httpReq.Header.Set("X-Request-ID", req.requestID)
+	}
+	if req.auth {
+		token, err := c.token()
+		if err != nil {
+			return nil, err
+		}
+		httpReq.Header.Set("Authorization", token)
+	}
+	return c.httpClient.Do(httpReq)
+}
+
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// retryable reports whether a failed attempt may succeed when tried again.
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		// The context is done, or the request could not be built
+		var urlErr *url.Error
+		return errors.As(err, &urlErr) && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+func retryDelay(attempt int) time.Duration {
+	return retryBaseDelay << attempt
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
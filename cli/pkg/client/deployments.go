@@ -0,0 +1,217 @@
+package client
+
+import (
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Deployment as returned by GetDeployment. The server sends the field names
+// of its model as they are.
+type Deployment struct {
+	ID                string
+	Name              string
+	Replicas          int
+	Balancer          string
+	RestartPolicy     string
+	RestartMaxRetries int
+	RestartCount      int
+	LastExitCode      int
+	LastExitReason    string
+	LastExitAt        *time.Time
+	CrashLooping      bool
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+}
+
+// DeploymentSummary is a deployment with the live status of its replicas, as
+// returned by ListDeployments.
+type DeploymentSummary struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	// running, degraded, stopped, crash-looping, not-deployed or unknown
+	Status        string    `json:"status"`
+	Replicas      int       `json:"replicas"`
+	Running       int       `json:"running"`
+	Balancer      string    `json:"balancer"`
+	RestartPolicy string    `json:"restart_policy"`
+	RestartCount  int       `json:"restart_count"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	Containers    []Replica `json:"containers"`
+}
+
+type Replica struct {
+	ContainerID string `json:"container_id"`
+	Address     string `json:"address"`
+	// Docker state of the container, missing if it no longer exists
+	State string `json:"state"`
+}
+
+type ListDeploymentsOptions struct {
+	ListOptions
+	// Only deployments with this in their name
+	Name string
+	// Only deployments with this status
+	Status string
+}
+
+type DeploymentList struct {
+	Deployments []DeploymentSummary `json:"deployments"`
+	Pagination
+}
+
+func deploymentPath(prefix, namespace, name string) string {
+	return prefix + url.PathEscape(namespace) + "/" + url.PathEscape(name)
+}
+
+func (c *Client) GetDeployment(ctx context.Context, namespace, name string) (*Deployment, error) {
+	var deployment Deployment
+	_, err := c.do(ctx, request{
+		method: http.MethodGet,
+		path:   deploymentPath("/deployment/", namespace, name),
+		auth:   true,
+	}, &deployment)
+	return &deployment, err
+}
+
+func (c *Client) ListDeployments(ctx context.Context, namespace string, opts ListDeploymentsOptions) (*DeploymentList, error) {
+	query := opts.query()
+	if opts.Name != "" {
+		query.Set("name", opts.Name)
+	}
+	if opts.Status != "" {
+		query.Set("status", opts.Status)
+	}
+	var list DeploymentList
+	_, err := c.do(ctx, request{
+		method: http.MethodGet,
+		path:   "/deployment/" + url.PathEscape(namespace),
+		query:  query,
+		auth:   true,
+	}, &list)
+	return &list, err
+}
+
+func (c *Client) CreateDeployment(ctx context.Context, namespace, name string) error {
+	_, err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/deployment/",
+		body:   map[string]string{"name": name, "namespace": namespace},
+		auth:   true,
+	}, nil)
+	return err
+}
+
+// DeleteDeployment deletes the deployment with its containers, images and
+// logs. The volumes of the containers are only removed with volumes.
+func (c *Client) DeleteDeployment(ctx context.Context, namespace, name string, volumes bool) error {
+	_, err := c.do(ctx, request{
+		method: http.MethodDelete,
+		path:   deploymentPath("/deployment/", namespace, name),
+		query:  url.Values{"volumes": {strconv.FormatBool(volumes)}},
+		auth:   true,
+		// Containers and images are removed before the server answers
+		noTimeout: true,
+	}, nil)
+	return err
+}
+
+type UploadOptions struct {
+	// File name of the archive sent to the server
+	Filename string
+	// Entrypoint of the app in the archive, the server default if empty
+	Entrypoint string
+	// ID the server logs the upload and its build with, it assigns one if
+	// empty
+	RequestID string
+}
+
+// Upload sends a zip archive of the build output, which the server builds and
+// deploys in the background.
+func (c *Client) Upload(ctx context.Context, namespace, name string, archive io.Reader, opts UploadOptions) error {
+	if opts.Filename == "" {
+		opts.Filename = "deployment.zip"
+	}
+	// The archive is read while it is sent, so that reading it reflects the
+	// progress of the upload
+	body, pipe := io.Pipe()
+	writer := multipart.NewWriter(pipe)
+	go func() {
+		pipe.CloseWithError(writeFormFile(writer, opts.Filename, archive))
+	}()
+	// Stops the writer if the request fails before it read the whole body
+	defer body.Close()
+
+	query := url.Values{}
+	if opts.Entrypoint != "" {
+		query.Set("entrypoint", opts.Entrypoint)
+	}
+	_, err := c.do(ctx, request{
+		method:      http.MethodPut,
+		path:        deploymentPath("/deployment/upload/", namespace, name),
+		query:       query,
+		rawBody:     body,
+		contentType: writer.FormDataContentType(),
+		auth:        true,
+		requestID:   opts.RequestID,
+		// Large archives take longer than the timeout
+		noTimeout: true,
+		// Every upload builds and deploys, a retry would deploy twice
+		noRetry: true,
+	}, nil)
+	return err
+}
+
+func writeFormFile(writer *multipart.Writer, filename string, file io.Reader) error {
+	part, err := writer.CreateFormFile("file", filename)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(part, file); err != nil {
+		return err
+	}
+	return writer.Close()
+}
+
+type ScaleResult struct {
+	Replicas int    `json:"replicas"`
+	Balancer string `json:"balancer"`
+}
+
+// Scale sets the number of replicas, and the load balancing strategy unless
+// balancer is empty.
+func (c *Client) Scale(ctx context.Context, namespace, name string, replicas int, balancer string) (*ScaleResult, error) {
+	var result ScaleResult
+	_, err := c.do(ctx, request{
+		method: http.MethodPut,
+		path:   deploymentPath("/deployment/scale/", namespace, name),
+		body:   map[string]interface{}{"replicas": replicas, "balancer": balancer},
+		auth:   true,
+		// Replicas are started and become ready before the server answers
+		noTimeout: true,
+	}, &result)
+	return &result, err
+}
+
+type RestartPolicy struct {
+	// no, on-failure or always
+	Policy string `json:"policy"`
+	// Restart attempts for on-failure, 0 for unlimited
+	MaxRetries int `json:"max_retries"`
+}
+
+func (c *Client) SetRestartPolicy(ctx context.Context, namespace, name string, policy RestartPolicy) (*RestartPolicy, error) {
+	var result RestartPolicy
+	_, err := c.do(ctx, request{
+		method: http.MethodPut,
+		path:   deploymentPath("/deployment/restart-policy/", namespace, name),
+		body:   policy,
+		auth:   true,
+	}, &result)
+	return &result, err
+}
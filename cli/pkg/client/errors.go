@@ -0,0 +1,106 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Codes of the errors of the server, see Error
+const (
+	CodeBadRequest        = "bad_request"
+	CodeUnauthorized      = "unauthorized"
+	CodeTokenExpired      = "token_expired"
+	CodeTokenRevoked      = "token_revoked"
+	CodeForbidden         = "forbidden"
+	CodeNotFound          = "not_found"
+	CodeMethodNotAllowed  = "method_not_allowed"
+	CodeConflict          = "conflict"
+	CodeNamespaceNotEmpty = "namespace_not_empty"
+	CodeLastOwner         = "last_owner"
+	CodeTooLarge          = "too_large"
+	CodeNotConfigured     = "not_configured"
+	CodeUpstream          = "upstream_error"
+	CodeInternal          = "internal"
+)
+
+// Error is an error response of the server.
+type Error struct {
+	// HTTP status of the response
+	Status  int                    `json:"status"`
+	Code    string                 `json:"code"`
+	Message string                 `json:"message"`
+	Details map[string]interface{} `json:"details,omitempty"`
+	// Identifies the request in the logs of the server
+	RequestID string `json:"request_id,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// ErrorCode returns the code of the server error in the chain of err, or an
+// empty string if there is none.
+func ErrorCode(err error) string {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr.Code
+	}
+	return ""
+}
+
+// IsNotFound reports whether err is a not_found error of the server.
+func IsNotFound(err error) bool {
+	return ErrorCode(err) == CodeNotFound
+}
+
+// decodeError reads an error response. Servers from before the error codes
+// respond with {"error": "message"} and get a code from the status.
+func decodeError(resp *http.Response) error {
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	apiErr := &Error{
+		Status:    resp.StatusCode,
+		Code:      statusCodes[resp.StatusCode],
+		Message:   strings.TrimSpace(string(body)),
+		RequestID: resp.Header.Get("X-Request-ID"),
+	}
+	if apiErr.Code == "" {
+		apiErr.Code = CodeInternal
+	}
+
+	var errResp struct {
+		Error *Error `json:"error"`
+	}
+	var legacyErrResp struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(body, &errResp) == nil && errResp.Error != nil && errResp.Error.Code != "" {
+		apiErr.Code = errResp.Error.Code
+		apiErr.Message = errResp.Error.Message
+		apiErr.Details = errResp.Error.Details
+		if errResp.Error.RequestID != "" {
+			apiErr.RequestID = errResp.Error.RequestID
+		}
+	} else if json.Unmarshal(body, &legacyErrResp) == nil && legacyErrResp.Error != "" {
+		apiErr.Message = legacyErrResp.Error
+	}
+	if apiErr.Message == "" {
+		apiErr.Message = resp.Status
+	}
+	return apiErr
+}
+
+var statusCodes = map[int]string{
+	http.StatusBadRequest:            CodeBadRequest,
+	http.StatusUnauthorized:          CodeUnauthorized,
+	http.StatusForbidden:             CodeForbidden,
+	http.StatusNotFound:              CodeNotFound,
+	http.StatusMethodNotAllowed:      CodeMethodNotAllowed,
+	http.StatusConflict:              CodeConflict,
+	http.StatusRequestEntityTooLarge: CodeTooLarge,
+}
@@ -0,0 +1,106 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+type LogLine struct {
+	// Short ID of the container of the replica
+	Container string `json:"container"`
+	// Image tag of the revision, only set for stored logs
+	Revision string `json:"revision,omitempty"`
+	// stdout or stderr
+	Stream string     `json:"stream"`
+	Time   *time.Time `json:"time,omitempty"`
+	Text   string     `json:"text"`
+}
+
+// LogStream reads log lines as the server sends them.
+type LogStream struct {
+	body io.ReadCloser
+	dec  *json.Decoder
+}
+
+// Next returns the next line, or io.EOF at the end of the stream.
+func (s *LogStream) Next() (LogLine, error) {
+	var line LogLine
+	err := s.dec.Decode(&line)
+	return line, err
+}
+
+func (s *LogStream) Close() error {
+	return s.body.Close()
+}
+
+type LogsOptions struct {
+	// Keep streaming new output until the context is done
+	Follow bool
+	// Only output since a duration like 10m or an RFC3339 time
+	Since string
+	// Number of lines from the end of each replica's output, all if empty
+	Tail       string
+	Timestamps bool
+}
+
+// Logs streams the output of the running replicas of the deployment.
+func (c *Client) Logs(ctx context.Context, namespace, name string, opts LogsOptions) (*LogStream, error) {
+	query := url.Values{}
+	query.Set("follow", strconv.FormatBool(opts.Follow))
+	query.Set("timestamps", strconv.FormatBool(opts.Timestamps))
+	if opts.Tail != "" {
+		query.Set("tail", opts.Tail)
+	}
+	if opts.Since != "" {
+		query.Set("since", opts.Since)
+	}
+	return c.logStream(ctx, deploymentPath("/deployment/", namespace, name)+"/logs", query)
+}
+
+type LogHistoryOptions struct {
+	// Revision ID or image tag
+	Revision string
+	// Durations like 10m or RFC3339 times
+	Since string
+	Until string
+	// Only lines containing this text
+	Contains string
+	// Only lines matching this regular expression
+	Regex string
+	// Maximum number of lines, the server default if zero
+	Limit int
+}
+
+// LogHistory searches the logs stored on the server, which includes the
+// output of replicas of previous revisions.
+func (c *Client) LogHistory(ctx context.Context, namespace, name string, opts LogHistoryOptions) (*LogStream, error) {
+	query := url.Values{}
+	for key, value := range map[string]string{
+		"revision": opts.Revision,
+		"since":    opts.Since,
+		"until":    opts.Until,
+		"contains": opts.Contains,
+		"regex":    opts.Regex,
+	} {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	if opts.Limit > 0 {
+		query.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	return c.logStream(ctx, deploymentPath("/deployment/", namespace, name)+"/logs/history", query)
+}
+
+func (c *Client) logStream(ctx context.Context, path string, query url.Values) (*LogStream, error) {
+	resp, err := c.do(ctx, request{method: http.MethodGet, path: path, query: query, auth: true, stream: true}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &LogStream{body: resp.Body, dec: json.NewDecoder(resp.Body)}, nil
+}
@@ -0,0 +1,147 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+type Namespace struct {
+	Name        string    `json:"name"`
+	Deployments int       `json:"deployments"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Pagination is the page of a list response.
+type Pagination struct {
+	Page    int `json:"page"`
+	PerPage int `json:"per_page"`
+	Pages   int `json:"pages"`
+	Total   int `json:"total"`
+}
+
+// ListOptions selects a page of a list, the server defaults are used for
+// zero values.
+type ListOptions struct {
+	Page    int
+	PerPage int
+}
+
+func (o ListOptions) query() url.Values {
+	query := url.Values{}
+	if o.Page > 0 {
+		query.Set("page", strconv.Itoa(o.Page))
+	}
+	if o.PerPage > 0 {
+		query.Set("per_page", strconv.Itoa(o.PerPage))
+	}
+	return query
+}
+
+type ListNamespacesOptions struct {
+	ListOptions
+	// Only namespaces with this in their name
+	Name string
+}
+
+type NamespaceList struct {
+	Namespaces []Namespace `json:"namespaces"`
+	Pagination
+}
+
+// ListNamespaces lists the namespaces the caller is a member of, or all
+// namespaces for admins.
+func (c *Client) ListNamespaces(ctx context.Context, opts ListNamespacesOptions) (*NamespaceList, error) {
+	query := opts.query()
+	if opts.Name != "" {
+		query.Set("name", opts.Name)
+	}
+	var list NamespaceList
+	_, err := c.do(ctx, request{method: http.MethodGet, path: "/namespace/", query: query, auth: true}, &list)
+	return &list, err
+}
+
+func (c *Client) CreateNamespace(ctx context.Context, name string) error {
+	_, err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/namespace/",
+		body:   map[string]string{"name": name},
+		auth:   true,
+	}, nil)
+	return err
+}
+
+type DeleteNamespaceOptions struct {
+	// Delete the deployments of the namespace too, namespaces with
+	// deployments are not deleted without it
+	Cascade bool
+	// Remove the volumes of the containers too
+	Volumes bool
+}
+
+type DeleteNamespaceResult struct {
+	DeletedDeployments int `json:"deleted_deployments"`
+}
+
+func (c *Client) DeleteNamespace(ctx context.Context, name string, opts DeleteNamespaceOptions) (*DeleteNamespaceResult, error) {
+	query := url.Values{}
+	query.Set("cascade", strconv.FormatBool(opts.Cascade))
+	query.Set("volumes", strconv.FormatBool(opts.Volumes))
+	var result DeleteNamespaceResult
+	_, err := c.do(ctx, request{
+		method: http.MethodDelete,
+		path:   "/namespace/" + url.PathEscape(name),
+		query:  query,
+		auth:   true,
+		// Deployments of the namespace are deleted before the server answers
+		noTimeout: true,
+	}, &result)
+	return &result, err
+}
+
+type User struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Member struct {
+	User User `json:"user"`
+	// viewer, deployer or owner
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (c *Client) ListMembers(ctx context.Context, namespace string) ([]Member, error) {
+	var members []Member
+	_, err := c.do(ctx, request{
+		method: http.MethodGet,
+		path:   "/namespace/" + url.PathEscape(namespace) + "/members",
+		auth:   true,
+	}, &members)
+	return members, err
+}
+
+// SetMember adds the user to the namespace or changes its role. Users that do
+// not exist yet are created.
+func (c *Client) SetMember(ctx context.Context, namespace, user, role string) (*Member, error) {
+	var member Member
+	_, err := c.do(ctx, request{
+		method: http.MethodPut,
+		path:   "/namespace/" + url.PathEscape(namespace) + "/members",
+		body:   map[string]string{"user": user, "role": role},
+		auth:   true,
+	}, &member)
+	return &member, err
+}
+
+func (c *Client) RemoveMember(ctx context.Context, namespace, user string) error {
+	_, err := c.do(ctx, request{
+		method: http.MethodDelete,
+		path:   "/namespace/" + url.PathEscape(namespace) + "/members/" + url.PathEscape(user),
+		auth:   true,
+	}, nil)
+	return err
+}
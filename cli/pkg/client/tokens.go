@@ -0,0 +1,67 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+type Token struct {
+	ID   string `json:"id"`
+	User User   `json:"user"`
+	Name string `json:"name"`
+	// First characters of the token, to tell tokens apart
+	Prefix string `json:"prefix"`
+	// Comma separated: read, deploy or admin
+	Scopes     string     `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+type CreateTokenRequest struct {
+	User string `json:"user"`
+	Name string `json:"name,omitempty"`
+	// Comma separated: read, deploy or admin
+	Scopes string `json:"scopes"`
+	// Go duration like 720h, tokens without it do not expire
+	ExpiresIn string `json:"expires_in,omitempty"`
+}
+
+type CreatedToken struct {
+	// The token itself, it is not shown again
+	Token   string `json:"token"`
+	Details Token  `json:"details"`
+}
+
+// ListUsers requires the admin scope, like all token methods.
+func (c *Client) ListUsers(ctx context.Context) ([]User, error) {
+	var users []User
+	_, err := c.do(ctx, request{method: http.MethodGet, path: "/admin/users", auth: true}, &users)
+	return users, err
+}
+
+// ListTokens lists the tokens of a user, or of all users if user is empty.
+func (c *Client) ListTokens(ctx context.Context, user string) ([]Token, error) {
+	query := url.Values{}
+	if user != "" {
+		query.Set("user", user)
+	}
+	var tokens []Token
+	_, err := c.do(ctx, request{method: http.MethodGet, path: "/admin/tokens", query: query, auth: true}, &tokens)
+	return tokens, err
+}
+
+// CreateToken creates a token for a user, creating the user if needed.
+func (c *Client) CreateToken(ctx context.Context, req CreateTokenRequest) (*CreatedToken, error) {
+	var token CreatedToken
+	_, err := c.do(ctx, request{method: http.MethodPost, path: "/admin/tokens", body: req, auth: true}, &token)
+	return &token, err
+}
+
+func (c *Client) RevokeToken(ctx context.Context, id string) error {
+	_, err := c.do(ctx, request{method: http.MethodDelete, path: "/admin/tokens/" + url.PathEscape(id), auth: true}, nil)
+	return err
+}
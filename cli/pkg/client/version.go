@@ -0,0 +1,83 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// APIVersion is the version of the API the client speaks, served under the
+// /v1 prefix.
+const APIVersion = 1
+
+// ServerVersion is the build of a server and the API versions it serves.
+type ServerVersion struct {
+	Version string `json:"version"`
+	// Git commit the server was built from, empty if unknown
+	Commit    string   `json:"commit,omitempty"`
+	GoVersion string   `json:"go_version"`
+	API       APIRange `json:"api"`
+	// Set by Negotiate for servers from before versioning, which report
+	// nothing else
+	Legacy bool `json:"legacy,omitempty"`
+}
+
+// APIRange is the range of API versions a server serves.
+type APIRange struct {
+	Min int `json:"min"`
+	Max int `json:"max"`
+}
+
+// Supports reports whether version is in the range.
+func (r APIRange) Supports(version int) bool {
+	return version >= r.Min && version <= r.Max
+}
+
+// IncompatibleError is returned by Negotiate for servers that do not serve
+// APIVersion.
+type IncompatibleError struct {
+	Server *ServerVersion
+}
+
+func (e *IncompatibleError) Error() string {
+	if e.Server.API.Max < APIVersion {
+		return fmt.Sprintf("the server %s serves API versions up to v%d, this client needs v%d", e.Server.Version, e.Server.API.Max, APIVersion)
+	}
+	return fmt.Sprintf("the server %s no longer serves API v%d, it serves v%d to v%d", e.Server.Version, APIVersion, e.Server.API.Min, e.Server.API.Max)
+}
+
+// ServerVersion returns the version of the server. Servers from before
+// versioning respond with a not_found error.
+func (c *Client) ServerVersion(ctx context.Context) (*ServerVersion, error) {
+	var version ServerVersion
+	_, err := c.do(ctx, request{method: http.MethodGet, path: "/version", unversioned: true}, &version)
+	if err != nil {
+		return nil, err
+	}
+	return &version, nil
+}
+
+// Negotiate checks that the server serves APIVersion, and returns an
+// *IncompatibleError if it does not. Servers from before versioning are
+// talked to on their unversioned paths from then on, their version only has
+// Legacy set.
+func (c *Client) Negotiate(ctx context.Context) (*ServerVersion, error) {
+	version, err := c.ServerVersion(ctx)
+	if IsNotFound(err) {
+		c.UseLegacyPaths()
+		return &ServerVersion{Legacy: true}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !version.API.Supports(APIVersion) {
+		return version, &IncompatibleError{Server: version}
+	}
+	return version, nil
+}
+
+// UseLegacyPaths makes the client send requests to the unversioned paths
+// servers served before versioning. Negotiate calls it for such servers.
+func (c *Client) UseLegacyPaths() {
+	c.apiPrefix = ""
+}
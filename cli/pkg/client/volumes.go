@@ -0,0 +1,111 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Volume is mounted into every replica of a deployment and keeps its data
+// across deploys.
+type Volume struct {
+	Name string `json:"name"`
+	// Absolute path in the containers
+	Path string `json:"path"`
+}
+
+type Snapshot struct {
+	ID        string    `json:"id"`
+	Volume    string    `json:"volume"`
+	SizeBytes int64     `json:"size_bytes"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type volumesResponse struct {
+	Volumes []Volume `json:"volumes"`
+}
+
+type snapshotsResponse struct {
+	Snapshots []Snapshot `json:"snapshots"`
+}
+
+type RestoreResult struct {
+	Snapshot string `json:"snapshot"`
+	Volume   string `json:"volume"`
+}
+
+func (c *Client) Volumes(ctx context.Context, namespace, name string) ([]Volume, error) {
+	var resp volumesResponse
+	_, err := c.do(ctx, request{
+		method: http.MethodGet,
+		path:   deploymentPath("/deployment/", namespace, name) + "/volumes",
+		auth:   true,
+	}, &resp)
+	return resp.Volumes, err
+}
+
+// SetVolumes replaces the volumes of a deployment. Running replicas are
+// replaced by ones with the new volumes.
+func (c *Client) SetVolumes(ctx context.Context, namespace, name string, volumes []Volume) ([]Volume, error) {
+	if volumes == nil {
+		volumes = []Volume{}
+	}
+	var resp volumesResponse
+	_, err := c.do(ctx, request{
+		method: http.MethodPut,
+		path:   deploymentPath("/deployment/", namespace, name) + "/volumes",
+		body:   volumesResponse{Volumes: volumes},
+		auth:   true,
+		// Replicas are rolled out before the server answers
+		noTimeout: true,
+	}, &resp)
+	return resp.Volumes, err
+}
+
+// Snapshots lists the snapshots of the volumes of a deployment, of a single
+// volume unless volume is empty, newest first.
+func (c *Client) Snapshots(ctx context.Context, namespace, name, volume string) ([]Snapshot, error) {
+	query := url.Values{}
+	if volume != "" {
+		query.Set("volume", volume)
+	}
+	var resp snapshotsResponse
+	_, err := c.do(ctx, request{
+		method: http.MethodGet,
+		path:   deploymentPath("/deployment/", namespace, name) + "/snapshots",
+		query:  query,
+		auth:   true,
+	}, &resp)
+	return resp.Snapshots, err
+}
+
+// CreateSnapshot takes a snapshot of a volume. The server removes the oldest
+// snapshots of the volume beyond its retention.
+func (c *Client) CreateSnapshot(ctx context.Context, namespace, name, volume string) (*Snapshot, error) {
+	var snapshot Snapshot
+	_, err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   deploymentPath("/deployment/", namespace, name) + "/snapshots",
+		body:   map[string]string{"volume": volume},
+		auth:   true,
+		// Large volumes take longer than the timeout
+		noTimeout: true,
+	}, &snapshot)
+	return &snapshot, err
+}
+
+// RestoreSnapshot replaces the content of a volume with a snapshot, the volume
+// of the snapshot if volume is empty. The deployment is stopped meanwhile.
+func (c *Client) RestoreSnapshot(ctx context.Context, namespace, name, snapshot, volume string) (*RestoreResult, error) {
+	var result RestoreResult
+	_, err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   deploymentPath("/deployment/", namespace, name) + "/snapshots/" + url.PathEscape(snapshot) + "/restore",
+		body:   map[string]string{"volume": volume},
+		auth:   true,
+		// Large volumes take longer than the timeout
+		noTimeout: true,
+	}, &result)
+	return &result, err
+}
@@ -0,0 +1,201 @@
+package keyring
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	kr "github.com/zalando/go-keyring"
+	"golang.org/x/crypto/scrypt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+var ErrWrongPassphrase = errors.New("wrong passphrase for the credential file")
+
+// fileBackend keeps secrets in a file encrypted with AES-GCM, with the key
+// derived from a passphrase using scrypt.
+type fileBackend struct {
+	path       string
+	passphrase func(create bool) (string, error)
+
+	mu      sync.Mutex
+	loaded  bool
+	key     []byte
+	salt    []byte
+	secrets map[string]string
+}
+
+type encryptedFile struct {
+	Salt  []byte `json:"salt"`
+	Nonce []byte `json:"nonce"`
+	Data  []byte `json:"data"`
+}
+
+func newFileBackend(path string, passphrase func(create bool) (string, error)) *fileBackend {
+	return &fileBackend{path: path, passphrase: passphrase}
+}
+
+func secretName(service, key string) string {
+	return service + "/" + key
+}
+
+func (f *fileBackend) get(service, key string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	// Nothing to decrypt, so don't ask for the passphrase
+	if !f.loaded {
+		if _, err := os.Stat(f.path); os.IsNotExist(err) {
+			return "", kr.ErrNotFound
+		}
+	}
+	if err := f.load(); err != nil {
+		return "", err
+	}
+	secret, ok := f.secrets[secretName(service, key)]
+	if !ok {
+		return "", kr.ErrNotFound
+	}
+	return secret, nil
+}
+
+func (f *fileBackend) set(service, key, value string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.load(); err != nil {
+		return err
+	}
+	f.secrets[secretName(service, key)] = value
+	return f.save()
+}
+
+func (f *fileBackend) delete(service, key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.loaded {
+		if _, err := os.Stat(f.path); os.IsNotExist(err) {
+			return kr.ErrNotFound
+		}
+	}
+	if err := f.load(); err != nil {
+		return err
+	}
+	name := secretName(service, key)
+	if _, ok := f.secrets[name]; !ok {
+		return kr.ErrNotFound
+	}
+	delete(f.secrets, name)
+	return f.save()
+}
+
+func (f *fileBackend) load() error {
+	if f.loaded {
+		return nil
+	}
+	if f.passphrase == nil {
+		return errors.New("no passphrase available for the credential file")
+	}
+
+	data, err := os.ReadFile(f.path)
+	if os.IsNotExist(err) {
+		passphrase, err := f.passphrase(true)
+		if err != nil {
+			return err
+		}
+		f.salt = make([]byte, 16)
+		if _, err := rand.Read(f.salt); err != nil {
+			return err
+		}
+		if f.key, err = deriveKey(passphrase, f.salt); err != nil {
+			return err
+		}
+		f.secrets = make(map[string]string)
+		f.loaded = true
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var file encryptedFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("invalid credential file %s: %w", f.path, err)
+	}
+	passphrase, err := f.passphrase(false)
+	if err != nil {
+		return err
+	}
+	key, err := deriveKey(passphrase, file.Salt)
+	if err != nil {
+		return err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return err
+	}
+	plain, err := gcm.Open(nil, file.Nonce, file.Data, nil)
+	if err != nil {
+		return ErrWrongPassphrase
+	}
+	secrets := make(map[string]string)
+	if err := json.Unmarshal(plain, &secrets); err != nil {
+		return fmt.Errorf("invalid credential file %s: %w", f.path, err)
+	}
+
+	f.key = key
+	f.salt = file.Salt
+	f.secrets = secrets
+	f.loaded = true
+	return nil
+}
+
+func (f *fileBackend) save() error {
+	plain, err := json.Marshal(f.secrets)
+	if err != nil {
+		return err
+	}
+	gcm, err := newGCM(f.key)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	data, err := json.Marshal(encryptedFile{
+		Salt:  f.salt,
+		Nonce: nonce,
+		Data:  gcm.Seal(nil, nonce, plain, nil),
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(f.path), 0700); err != nil {
+		return err
+	}
+	// Write to a temporary file first so a failed write keeps the old secrets
+	tmp := f.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, f.path)
+}
+
+func deriveKey(passphrase string, salt []byte) ([]byte, error) {
+	if passphrase == "" {
+		return nil, errors.New("passphrase of the credential file must not be empty")
+	}
+	return scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, 32)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,127 @@
+package keyring
+
+import (
+	"encoding/json"
+	"errors"
+	kr "github.com/zalando/go-keyring"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func passphrase(value string) func(bool) (string, error) {
+	return func(bool) (string, error) {
+		return value, nil
+	}
+}
+
+// writeSecret creates a credential file with a single secret and returns its
+// path.
+func writeSecret(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "credentials")
+	if err := newFileBackend(path, passphrase("secret")).set("bulut", "token", "value"); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFileBackendRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials")
+	backend := newFileBackend(path, passphrase("secret"))
+
+	if _, err := backend.get("bulut", "token"); !errors.Is(err, kr.ErrNotFound) {
+		t.Fatalf("get before set: got %v, want ErrNotFound", err)
+	}
+	if err := backend.set("bulut", "token", "value"); err != nil {
+		t.Fatal(err)
+	}
+	if err := backend.set("bulut", "refresh", "other"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A new backend has to read the secrets from the file
+	reopened := newFileBackend(path, passphrase("secret"))
+	value, err := reopened.get("bulut", "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != "value" {
+		t.Fatalf("got %q, want %q", value, "value")
+	}
+
+	if err := reopened.delete("bulut", "token"); err != nil {
+		t.Fatal(err)
+	}
+	if err := reopened.delete("bulut", "token"); !errors.Is(err, kr.ErrNotFound) {
+		t.Fatalf("second delete: got %v, want ErrNotFound", err)
+	}
+
+	reopened = newFileBackend(path, passphrase("secret"))
+	if _, err := reopened.get("bulut", "token"); !errors.Is(err, kr.ErrNotFound) {
+		t.Fatalf("get after delete: got %v, want ErrNotFound", err)
+	}
+	value, err = reopened.get("bulut", "refresh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != "other" {
+		t.Fatalf("got %q, want %q", value, "other")
+	}
+}
+
+func TestFileBackendWrongPassphrase(t *testing.T) {
+	path := writeSecret(t)
+
+	_, err := newFileBackend(path, passphrase("wrong")).get("bulut", "token")
+	if !errors.Is(err, ErrWrongPassphrase) {
+		t.Fatalf("got %v, want ErrWrongPassphrase", err)
+	}
+}
+
+func TestFileBackendCorruptedFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		corrupt func(data []byte) []byte
+	}{
+		{"truncated", func(data []byte) []byte {
+			return data[:len(data)/2]
+		}},
+		{"empty", func(data []byte) []byte {
+			return nil
+		}},
+		{"modified ciphertext", func(data []byte) []byte {
+			var file encryptedFile
+			if err := json.Unmarshal(data, &file); err != nil {
+				t.Fatal(err)
+			}
+			file.Data[0] ^= 0xff
+			data, err := json.Marshal(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return data
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeSecret(t)
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, tt.corrupt(data), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			backend := newFileBackend(path, passphrase("secret"))
+			if _, err := backend.get("bulut", "token"); err == nil {
+				t.Fatal("expected an error for a corrupted file")
+			}
+			// The secrets cannot be read, so they must not be overwritten
+			if err := backend.set("bulut", "token", "new"); err == nil {
+				t.Fatal("expected set to fail for a corrupted file")
+			}
+		})
+	}
+}
@@ -1,23 +1,83 @@
 package keyring
 
-import kr "github.com/zalando/go-keyring"
+import (
+	"errors"
+	kr "github.com/zalando/go-keyring"
+	"os"
+	"path/filepath"
+)
 
-func Open(serviceName string) (Keyring, error) {
-	return Keyring{
+const (
+	BackendAuto = "auto"
+	BackendOS   = "os"
+	BackendFile = "file"
+)
+
+// Options select where secrets are stored.
+type Options struct {
+	// One of BackendAuto, BackendOS or BackendFile. Auto uses the keyring of
+	// the OS and falls back to the encrypted file when it is not available,
+	// like on servers without a Secret Service.
+	Backend string
+	// Path of the encrypted file, defaults to <user config dir>/bulut/credentials
+	FilePath string
+	// Asks for the passphrase of the encrypted file. It is only called when the
+	// file is first read or created.
+	Passphrase func(create bool) (string, error)
+}
+
+type backend interface {
+	get(service, key string) (string, error)
+	set(service, key, value string) error
+	delete(service, key string) error
+}
+
+func Open(serviceName string, opts Options) (Keyring, error) {
+	k := Keyring{
 		ServiceName:      serviceName,
 		ErrNotFound:      kr.ErrNotFound,
 		ErrSetDataTooBig: kr.ErrSetDataTooBig,
-	}, nil
+	}
+
+	backendName := opts.Backend
+	if backendName == "" || backendName == BackendAuto {
+		backendName = BackendOS
+		if !osKeyringAvailable(serviceName) {
+			backendName = BackendFile
+		}
+	}
+
+	switch backendName {
+	case BackendOS:
+		k.backend = osBackend{}
+	case BackendFile:
+		path := opts.FilePath
+		if path == "" {
+			configDir, err := os.UserConfigDir()
+			if err != nil {
+				return k, err
+			}
+			path = filepath.Join(configDir, "bulut", "credentials")
+		}
+		k.backend = newFileBackend(path, opts.Passphrase)
+	default:
+		return k, errors.New("unknown keyring backend: " + backendName)
+	}
+	k.Backend = backendName
+	return k, nil
 }
 
 type Keyring struct {
 	ServiceName      string
 	ErrNotFound      error
 	ErrSetDataTooBig error
+	// Name of the backend in use
+	Backend string
+	backend backend
 }
 
 func (k Keyring) Get(key string) (string, error) {
-	secret, err := kr.Get(k.ServiceName, key)
+	secret, err := k.backend.get(k.ServiceName, key)
 	if err != nil {
 		return "", err
 	}
@@ -25,9 +85,30 @@ func (k Keyring) Get(key string) (string, error) {
 }
 
 func (k Keyring) Set(key string, value string) error {
-	return kr.Set(k.ServiceName, key, value)
+	return k.backend.set(k.ServiceName, key, value)
 }
 
 func (k Keyring) Delete(key string) error {
-	return kr.Delete(k.ServiceName, key)
+	return k.backend.delete(k.ServiceName, key)
+}
+
+// osKeyringAvailable checks that the keyring of the OS can be reached, which
+// fails for example when there is no D-Bus session.
+func osKeyringAvailable(serviceName string) bool {
+	_, err := kr.Get(serviceName, "bulut-keyring-probe")
+	return err == nil || errors.Is(err, kr.ErrNotFound)
+}
+
+type osBackend struct{}
+
+func (osBackend) get(service, key string) (string, error) {
+	return kr.Get(service, key)
+}
+
+func (osBackend) set(service, key, value string) error {
+	return kr.Set(service, key, value)
+}
+
+func (osBackend) delete(service, key string) error {
+	return kr.Delete(service, key)
 }
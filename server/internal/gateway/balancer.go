@@ -0,0 +1,81 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sync/atomic"
+)
+
+type Strategy string
+
+const (
+	RoundRobin       Strategy = "round-robin"
+	LeastConnections Strategy = "least-connections"
+)
+
+func (s Strategy) Valid() bool {
+	return s == RoundRobin || s == LeastConnections
+}
+
+// Backend is a single replica behind the gateway.
+type Backend struct {
+	Address string
+	proxy   *httputil.ReverseProxy
+	active  int64
+}
+
+func newBackend(address string) (*Backend, error) {
+	target, err := url.Parse("http://" + address)
+	if err != nil {
+		return nil, err
+	}
+	return &Backend{
+		Address: address,
+		proxy:   httputil.NewSingleHostReverseProxy(target),
+	}, nil
+}
+
+// ActiveConnections returns the number of requests currently being proxied.
+func (b *Backend) ActiveConnections() int64 {
+	return atomic.LoadInt64(&b.active)
+}
+
+func (b *Backend) serve(w http.ResponseWriter, r *http.Request) {
+	atomic.AddInt64(&b.active, 1)
+	defer atomic.AddInt64(&b.active, -1)
+	b.proxy.ServeHTTP(w, r)
+}
+
+type pool struct {
+	strategy Strategy
+	backends []*Backend
+	next     uint64
+}
+
+func newPool(strategy Strategy, backends []*Backend) *pool {
+	if !strategy.Valid() {
+		strategy = RoundRobin
+	}
+	return &pool{
+		strategy: strategy,
+		backends: backends,
+	}
+}
+
+func (p *pool) pick() *Backend {
+	if len(p.backends) == 0 {
+		return nil
+	}
+	if p.strategy == LeastConnections {
+		best := p.backends[0]
+		for _, b := range p.backends[1:] {
+			if b.ActiveConnections() < best.ActiveConnections() {
+				best = b
+			}
+		}
+		return best
+	}
+	n := atomic.AddUint64(&p.next, 1)
+	return p.backends[(n-1)%uint64(len(p.backends))]
+}
@@ -0,0 +1,132 @@
+package gateway
+
+import (
+	"bulut-server/pkg/logger"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type Config struct {
+	Host   string
+	Port   int
+	Domain string
+}
+
+// Gateway is a reverse proxy in front of deployment replicas. Requests are
+// routed by host name, <deployment>.<namespace>.<domain>, and balanced
+// between the replicas of the matching deployment.
+type Gateway struct {
+	config *Config
+	logger *logger.Logger
+	mu     sync.RWMutex
+	routes map[string]*pool
+	// Why the gateway is not serving, nil while it is
+	status error
+}
+
+var errNotStarted = errors.New("gateway is not started")
+
+func New(config *Config, logger *logger.Logger) *Gateway {
+	return &Gateway{
+		config: config,
+		logger: logger,
+		routes: make(map[string]*pool),
+		status: errNotStarted,
+	}
+}
+
+// Hostname returns the host name the deployment is served on.
+func (g *Gateway) Hostname(namespace, deployment string) string {
+	return strings.ToLower(deployment + "." + namespace + "." + g.config.Domain)
+}
+
+// SetRoute replaces the backends of a route. Backends that are still present
+// keep their connection counters.
+func (g *Gateway) SetRoute(hostname string, strategy Strategy, addresses []string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	existing := make(map[string]*Backend)
+	if p, ok := g.routes[hostname]; ok {
+		for _, b := range p.backends {
+			existing[b.Address] = b
+		}
+	}
+
+	backends := make([]*Backend, 0, len(addresses))
+	for _, address := range addresses {
+		if b, ok := existing[address]; ok {
+			backends = append(backends, b)
+			continue
+		}
+		b, err := newBackend(address)
+		if err != nil {
+			g.logger.Error(err, "Failed to create gateway backend", "host", hostname, "address", address)
+			continue
+		}
+		backends = append(backends, b)
+	}
+
+	g.routes[hostname] = newPool(strategy, backends)
+	g.logger.Info("Updated gateway route", "host", hostname, "strategy", strategy, "backends", addresses)
+}
+
+func (g *Gateway) RemoveRoute(hostname string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.routes, hostname)
+}
+
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	hostname := r.Host
+	if host, _, err := net.SplitHostPort(hostname); err == nil {
+		hostname = host
+	}
+
+	g.mu.RLock()
+	p, ok := g.routes[strings.ToLower(hostname)]
+	g.mu.RUnlock()
+	if !ok {
+		http.Error(w, "No deployment found for this host", http.StatusNotFound)
+		return
+	}
+
+	backend := p.pick()
+	if backend == nil {
+		http.Error(w, "No replicas available", http.StatusServiceUnavailable)
+		return
+	}
+	backend.serve(w, r)
+}
+
+func (g *Gateway) Start() error {
+	address := g.config.Host + ":" + strconv.Itoa(g.config.Port)
+	g.logger.Info("Starting gateway", "address", address, "domain", g.config.Domain)
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		g.setStatus(err)
+		return err
+	}
+	g.setStatus(nil)
+	err = http.Serve(listener, g)
+	g.setStatus(err)
+	return err
+}
+
+// Status returns nil while the gateway is serving, and why it is not
+// otherwise.
+func (g *Gateway) Status() error {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.status
+}
+
+func (g *Gateway) setStatus(err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.status = err
+}
@@ -0,0 +1,63 @@
+package audit
+
+import (
+	"bulut-server/pkg/orm/models"
+	"gorm.io/gorm"
+	"time"
+)
+
+const (
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+)
+
+func Record(db *gorm.DB, entry *models.AuditEntry) error {
+	return db.Create(entry).Error
+}
+
+type Filter struct {
+	Actor      string
+	Namespace  string
+	Deployment string
+	Action     string
+	Since      time.Time
+	Until      time.Time
+	Page       int
+	PerPage    int
+}
+
+// List returns a page of entries matching the filter, newest first, and the
+// total number of matching entries.
+func List(db *gorm.DB, filter Filter) ([]models.AuditEntry, int64, error) {
+	query := db.Model(&models.AuditEntry{})
+	if filter.Actor != "" {
+		query = query.Where("actor = ?", filter.Actor)
+	}
+	if filter.Namespace != "" {
+		query = query.Where("namespace = ?", filter.Namespace)
+	}
+	if filter.Deployment != "" {
+		query = query.Where("deployment = ?", filter.Deployment)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where("created_at >= ?", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		query = query.Where("created_at <= ?", filter.Until)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var entries []models.AuditEntry
+	result := query.Order("created_at desc").
+		Offset((filter.Page - 1) * filter.PerPage).
+		Limit(filter.PerPage).
+		Find(&entries)
+	return entries, total, result.Error
+}
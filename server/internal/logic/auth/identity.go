@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"strings"
+)
+
+type Scope string
+
+// Each scope includes the ones before it
+const (
+	ScopeRead   Scope = "read"
+	ScopeDeploy Scope = "deploy"
+	ScopeAdmin  Scope = "admin"
+)
+
+var scopeLevels = map[Scope]int{
+	ScopeRead:   1,
+	ScopeDeploy: 2,
+	ScopeAdmin:  3,
+}
+
+func (s Scope) Valid() bool {
+	_, ok := scopeLevels[s]
+	return ok
+}
+
+// ParseScopes parses a comma separated scope list.
+func ParseScopes(scopes string) []Scope {
+	var parsed []Scope
+	for _, scope := range strings.Split(scopes, ",") {
+		scope = strings.TrimSpace(scope)
+		if scope != "" {
+			parsed = append(parsed, Scope(scope))
+		}
+	}
+	return parsed
+}
+
+func JoinScopes(scopes []Scope) string {
+	strs := make([]string, len(scopes))
+	for i, scope := range scopes {
+		strs[i] = string(scope)
+	}
+	return strings.Join(strs, ",")
+}
+
+// Identity is the caller of an API request.
+type Identity struct {
+	UserID   uuid.UUID
+	UserName string
+	// Zero for the bootstrap API key
+	TokenID uuid.UUID
+	Scopes  []Scope
+}
+
+// Bootstrap is the identity of requests made with the API_KEY from the config.
+var Bootstrap = Identity{
+	UserName: "bootstrap",
+	Scopes:   []Scope{ScopeAdmin},
+}
+
+func (i Identity) IsBootstrap() bool {
+	return i.TokenID == uuid.Nil
+}
+
+// HasScope reports whether the identity has the scope or one that includes it.
+func (i Identity) HasScope(scope Scope) bool {
+	for _, s := range i.Scopes {
+		if scopeLevels[s] >= scopeLevels[scope] {
+			return true
+		}
+	}
+	return false
+}
+
+type identityKey struct{}
+
+func WithIdentity(ctx context.Context, identity Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+func FromContext(ctx context.Context) (Identity, bool) {
+	identity, ok := ctx.Value(identityKey{}).(Identity)
+	return identity, ok
+}
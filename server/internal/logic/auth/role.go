@@ -0,0 +1,44 @@
+package auth
+
+type Role string
+
+// Roles of a user in a namespace, each includes the ones before it
+const (
+	RoleViewer   Role = "viewer"
+	RoleDeployer Role = "deployer"
+	RoleOwner    Role = "owner"
+)
+
+var roleLevels = map[Role]int{
+	RoleViewer:   1,
+	RoleDeployer: 2,
+	RoleOwner:    3,
+}
+
+func (r Role) Valid() bool {
+	_, ok := roleLevels[r]
+	return ok
+}
+
+// Includes reports whether the role grants at least the other role.
+func (r Role) Includes(other Role) bool {
+	return roleLevels[r] >= roleLevels[other]
+}
+
+// Scope is the token scope needed to act with the role.
+func (r Role) Scope() Scope {
+	if r == RoleViewer {
+		return ScopeRead
+	}
+	return ScopeDeploy
+}
+
+// CanAccessNamespace reports whether the identity may act with the required
+// role in a namespace where its user has the member role. Admins may act in
+// every namespace, everyone else is limited by both role and token scopes.
+func (i Identity) CanAccessNamespace(member Role, required Role) bool {
+	if i.HasScope(ScopeAdmin) {
+		return true
+	}
+	return member.Includes(required) && i.HasScope(required.Scope())
+}
@@ -0,0 +1,119 @@
+package auth
+
+import (
+	"bulut-server/pkg/orm/common"
+	"bulut-server/pkg/orm/models"
+	"errors"
+	"gorm.io/gorm"
+	"time"
+)
+
+// Session is a short-lived API token with a refresh token to renew it.
+type Session struct {
+	Token            string      `json:"token"`
+	ExpiresAt        time.Time   `json:"expires_at"`
+	RefreshToken     string      `json:"refresh_token"`
+	RefreshExpiresAt time.Time   `json:"refresh_expires_at"`
+	User             models.User `json:"user"`
+	Scopes           string      `json:"scopes"`
+}
+
+// Name of the API tokens of sessions
+const SessionTokenName = "sso session"
+
+// CreateSession issues an API token valid for ttl and a refresh token valid
+// for refreshTTL. The groups are kept with the refresh token to compute the
+// grants again on refresh. Expired tokens of earlier sessions of the user are
+// removed.
+func CreateSession(db *gorm.DB, user models.User, scopes []Scope, groups []string, ttl, refreshTTL time.Duration) (Session, error) {
+	var session Session
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := pruneSessions(tx, user); err != nil {
+			return err
+		}
+
+		expiresAt := time.Now().Add(ttl)
+		token, plain, err := CreateToken(tx, user.Name, SessionTokenName, scopes, &expiresAt)
+		if err != nil {
+			return err
+		}
+
+		refreshPlain, refreshHash, err := GenerateToken()
+		if err != nil {
+			return err
+		}
+		refresh := models.RefreshToken{
+			UserID:     user.ID,
+			ApiTokenID: &token.ID,
+			Hash:       refreshHash,
+			Scopes:     token.Scopes,
+			Groups:     groups,
+			ExpiresAt:  time.Now().Add(refreshTTL),
+		}
+		if err := tx.Create(&refresh).Error; err != nil {
+			return err
+		}
+
+		session = Session{
+			Token:            plain,
+			ExpiresAt:        expiresAt,
+			RefreshToken:     refreshPlain,
+			RefreshExpiresAt: refresh.ExpiresAt,
+			User:             user,
+			Scopes:           token.Scopes,
+		}
+		return nil
+	})
+	return session, err
+}
+
+// RedeemRefreshToken revokes a refresh token together with the session token
+// it was issued with and returns it with its user. A refresh token can only
+// be redeemed once, the new session must be created in the same transaction.
+func RedeemRefreshToken(tx *gorm.DB, refreshToken string) (models.RefreshToken, error) {
+	var refresh models.RefreshToken
+	err := tx.Preload("User").Where("hash = ?", HashToken(refreshToken)).First(&refresh).Error
+	if err != nil {
+		if errors.Is(err, common.ErrNotFound) {
+			return refresh, ErrInvalidToken
+		}
+		return refresh, err
+	}
+	if refresh.RevokedAt != nil {
+		return refresh, ErrTokenRevoked
+	}
+	if time.Now().After(refresh.ExpiresAt) {
+		return refresh, ErrTokenExpired
+	}
+
+	// Only one concurrent refresh can revoke the token
+	now := time.Now()
+	result := tx.Model(&refresh).Where("revoked_at IS NULL").Update("revoked_at", now)
+	if result.Error != nil {
+		return refresh, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return refresh, ErrTokenRevoked
+	}
+	if refresh.ApiTokenID != nil {
+		err := tx.Model(&models.ApiToken{}).
+			Where("id = ? AND revoked_at IS NULL", *refresh.ApiTokenID).
+			Update("revoked_at", now).Error
+		if err != nil {
+			return refresh, err
+		}
+	}
+	return refresh, nil
+}
+
+// pruneSessions deletes the expired session and refresh tokens of the user.
+func pruneSessions(tx *gorm.DB, user models.User) error {
+	now := time.Now()
+	err := tx.Unscoped().
+		Where("user_id = ? AND name = ? AND expires_at < ?", user.ID, SessionTokenName, now).
+		Delete(&models.ApiToken{}).Error
+	if err != nil {
+		return err
+	}
+	return tx.Unscoped().Where("user_id = ? AND expires_at < ?", user.ID, now).Delete(&models.RefreshToken{}).Error
+}
@@ -0,0 +1,134 @@
+package auth
+
+import (
+	"bulut-server/pkg/orm/common"
+	"bulut-server/pkg/orm/models"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"time"
+)
+
+const tokenPrefix = "bulut_"
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrTokenExpired = errors.New("token expired")
+	ErrTokenRevoked = errors.New("token revoked")
+)
+
+// GenerateToken returns a new random token and its hash.
+func GenerateToken() (string, string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", err
+	}
+	token := tokenPrefix + hex.EncodeToString(secret)
+	return token, HashToken(token), nil
+}
+
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func FindUser(db *gorm.DB, name string) (models.User, error) {
+	var user models.User
+	result := db.Where("name = ?", name).First(&user)
+	return user, result.Error
+}
+
+func FindOrCreateUser(db *gorm.DB, name string) (models.User, error) {
+	var user models.User
+	result := db.Where(models.User{Name: name}).FirstOrCreate(&user)
+	return user, result.Error
+}
+
+func ListUsers(db *gorm.DB) ([]models.User, error) {
+	var users []models.User
+	result := db.Order("name asc").Find(&users)
+	return users, result.Error
+}
+
+// CreateToken mints a token for the user, creating the user if needed. The
+// returned string is the only time the token is available in plain text.
+func CreateToken(db *gorm.DB, userName, name string, scopes []Scope, expiresAt *time.Time) (models.ApiToken, string, error) {
+	user, err := FindOrCreateUser(db, userName)
+	if err != nil {
+		return models.ApiToken{}, "", err
+	}
+
+	plain, hash, err := GenerateToken()
+	if err != nil {
+		return models.ApiToken{}, "", err
+	}
+	token := models.ApiToken{
+		UserID:    user.ID,
+		User:      user,
+		Name:      name,
+		Prefix:    plain[:len(tokenPrefix)+6],
+		Hash:      hash,
+		Scopes:    JoinScopes(scopes),
+		ExpiresAt: expiresAt,
+	}
+	result := db.Create(&token)
+	return token, plain, result.Error
+}
+
+func ListTokens(db *gorm.DB, userName string) ([]models.ApiToken, error) {
+	var tokens []models.ApiToken
+	query := db.Preload("User").Order("created_at desc")
+	if userName != "" {
+		query = query.Joins("JOIN users ON users.id = api_tokens.user_id").Where("users.name = ?", userName)
+	}
+	result := query.Find(&tokens)
+	return tokens, result.Error
+}
+
+func RevokeToken(db *gorm.DB, id uuid.UUID) (models.ApiToken, error) {
+	var token models.ApiToken
+	if err := db.Preload("User").First(&token, "id = ?", id).Error; err != nil {
+		return token, err
+	}
+	if token.RevokedAt != nil {
+		return token, nil
+	}
+	now := time.Now()
+	token.RevokedAt = &now
+	result := db.Model(&token).Update("revoked_at", now)
+	return token, result.Error
+}
+
+// Authenticate resolves a plain text token to the identity it belongs to.
+func Authenticate(db *gorm.DB, plain string) (Identity, error) {
+	var token models.ApiToken
+	err := db.Preload("User").Where("hash = ?", HashToken(plain)).First(&token).Error
+	if err != nil {
+		if errors.Is(err, common.ErrNotFound) {
+			return Identity{}, ErrInvalidToken
+		}
+		return Identity{}, err
+	}
+
+	now := time.Now()
+	if token.RevokedAt != nil {
+		return Identity{}, ErrTokenRevoked
+	}
+	if token.ExpiresAt != nil && now.After(*token.ExpiresAt) {
+		return Identity{}, ErrTokenExpired
+	}
+
+	if err := db.Model(&token).UpdateColumn("last_used_at", now).Error; err != nil {
+		return Identity{}, err
+	}
+
+	return Identity{
+		UserID:   token.UserID,
+		UserName: token.User.Name,
+		TokenID:  token.ID,
+		Scopes:   ParseScopes(token.Scopes),
+	}, nil
+}
@@ -0,0 +1,132 @@
+package deploy
+
+import (
+	"bulut-server/pkg/orm/models"
+	"errors"
+	"fmt"
+	docker "github.com/fsouza/go-dockerclient"
+	"gorm.io/gorm"
+)
+
+var ErrNamespaceNotEmpty = errors.New("namespace has deployments")
+
+// DeleteDeployment removes the containers and images of a deployment, frees
+// its hostname on the gateway and soft-deletes it with its revisions. Volumes
+// of the deployment and anonymous volumes of the containers are only removed
+// if removeVolumes is set.
+func DeleteDeployment(opts ReplicaOpts, deployment models.Deployment, removeVolumes bool) error {
+	defer lockDeployment(deployment.ID)()
+
+	if deployment.Namespace.Name == "" {
+		if err := opts.Db.Preload("Namespace").First(&deployment, "id = ?", deployment.ID).Error; err != nil {
+			return err
+		}
+	}
+
+	containers, err := FindContainers(opts.Db, deployment)
+	if err != nil {
+		return err
+	}
+	for _, container := range containers {
+		if err := removeContainer(container.ContainerID, removeVolumes); err != nil {
+			return err
+		}
+		if err := opts.Db.Delete(&container).Error; err != nil {
+			return err
+		}
+	}
+	if deployment.ContainerID != "" {
+		if err := removeContainer(deployment.ContainerID, removeVolumes); err != nil {
+			return err
+		}
+	}
+	if removeVolumes {
+		if err := removeDockerVolumes(deployment); err != nil {
+			return err
+		}
+	}
+	if opts.Gateway != nil {
+		opts.Gateway.RemoveRoute(opts.Gateway.Hostname(deployment.Namespace.Name, deployment.Name))
+	}
+
+	var revisions []models.Revision
+	if err := opts.Db.Where("deployment_id = ?", deployment.ID).Find(&revisions).Error; err != nil {
+		return err
+	}
+	removeImages(opts, revisions)
+
+	return opts.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("deployment_id = ?", deployment.ID).Delete(&models.Revision{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("deployment_id = ?", deployment.ID).Delete(&models.Volume{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&deployment).Error
+	})
+}
+
+// DeleteNamespace soft-deletes a namespace and its members. A namespace with
+// deployments is only deleted with cascade, which deletes the deployments
+// first. The deleted deployments are returned.
+func DeleteNamespace(opts ReplicaOpts, namespace models.Namespace, cascade, removeVolumes bool) ([]models.Deployment, error) {
+	var deployments []models.Deployment
+	if err := opts.Db.Where("namespace_id = ?", namespace.ID).Find(&deployments).Error; err != nil {
+		return nil, err
+	}
+	if len(deployments) > 0 && !cascade {
+		return nil, ErrNamespaceNotEmpty
+	}
+
+	var deleted []models.Deployment
+	for _, deployment := range deployments {
+		deployment.Namespace = namespace
+		if err := DeleteDeployment(opts, deployment, removeVolumes); err != nil {
+			return deleted, fmt.Errorf("failed to delete deployment %s: %w", deployment.Name, err)
+		}
+		deleted = append(deleted, deployment)
+	}
+
+	err := opts.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("namespace_id = ?", namespace.ID).Delete(&models.NamespaceMember{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&namespace).Error
+	})
+	return deleted, err
+}
+
+// removeContainer is DeleteContainer for containers that may be gone already.
+func removeContainer(containerID string, removeVolumes bool) error {
+	err := DeleteContainer(containerID, removeVolumes)
+	var noSuchContainer *docker.NoSuchContainer
+	if errors.As(err, &noSuchContainer) {
+		return nil
+	}
+	return err
+}
+
+// removeImages removes the images of all revisions. Failures are only logged,
+// the deployment is deleted either way.
+func removeImages(opts ReplicaOpts, revisions []models.Revision) {
+	if len(revisions) == 0 {
+		return
+	}
+	client, err := docker.NewClientFromEnv()
+	if err != nil {
+		opts.Logger.Error(err, "Failed to connect to docker to remove images")
+		return
+	}
+
+	// The latest tag is shared by all revisions of a deployment
+	images := []string{fmt.Sprintf("%s:latest", revisions[0].ImageName)}
+	for _, rev := range revisions {
+		images = append(images, fmt.Sprintf("%s:%s", rev.ImageName, rev.ImageTag))
+	}
+	for _, image := range images {
+		err := client.RemoveImageExtended(image, docker.RemoveImageOptions{Force: true})
+		if err != nil && !errors.Is(err, docker.ErrNoSuchImage) {
+			opts.Logger.Error(err, "Failed to remove image", "image", image)
+		}
+	}
+}
@@ -2,13 +2,21 @@ package deploy
 
 import (
 	"archive/zip"
+	"bulut-server/internal/gateway"
 	"bulut-server/internal/logic/revision"
+	"bulut-server/internal/metrics"
+	"bulut-server/internal/tracing"
 	"bulut-server/pkg/logger"
 	"bulut-server/pkg/orm/models"
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	docker "github.com/fsouza/go-dockerclient"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"gorm.io/gorm"
 	"io"
 	"math/rand"
@@ -22,6 +30,8 @@ import (
 
 const DEFAULT_DEPLOY_PORT = 1234
 
+var tracer = otel.Tracer("bulut-server/internal/logic/deploy")
+
 func GenerateTempFilename() string {
 	rand.Seed(time.Now().UnixNano())
 	randID := fmt.Sprintf("%016x", rand.Uint64())
@@ -110,7 +120,12 @@ type ImageBuildResult struct {
 	Image     *docker.Image
 }
 
-func BuildDockerImage(imageRepo, tempDir string) (*ImageBuildResult, error) {
+func BuildDockerImage(ctx context.Context, imageRepo, tempDir string) (result *ImageBuildResult, err error) {
+	ctx, span := tracer.Start(ctx, "build image", trace.WithAttributes(attribute.String("bulut.image", imageRepo)))
+	defer func() {
+		tracing.End(span, err)
+	}()
+
 	imageTag := time.Now().Format("20060102150405")
 	imageName := fmt.Sprintf("%s:%s", imageRepo, imageTag)
 	buildOpts := docker.BuildImageOptions{
@@ -125,22 +140,28 @@ func BuildDockerImage(imageRepo, tempDir string) (*ImageBuildResult, error) {
 		return nil, err
 	}
 
+	_, buildSpan := tracer.Start(ctx, "docker build")
 	err = client.BuildImage(buildOpts)
+	tracing.End(buildSpan, err)
 	if err != nil {
 		return nil, err
 	}
 
 	// Tag image as latest
+	_, tagSpan := tracer.Start(ctx, "docker tag")
 	err = client.TagImage(imageName, docker.TagImageOptions{
 		Repo:  imageRepo,
 		Tag:   "latest",
 		Force: true,
 	})
+	tracing.End(tagSpan, err)
 	if err != nil {
 		return nil, err
 	}
 
+	_, inspectSpan := tracer.Start(ctx, "docker inspect")
 	image, err := client.InspectImage(imageName)
+	tracing.End(inspectSpan, err)
 	if err != nil {
 		return nil, err
 	}
@@ -159,7 +180,7 @@ type ContainerDeployResult struct {
 	IP          string // Deprecated: Gateway/Domains will be used instead
 }
 
-func DeployDockerContainer(imageName, containerName string) (*ContainerDeployResult, error) {
+func DeployDockerContainer(ctx context.Context, imageName, containerName string, labels map[string]string, restartPolicy docker.RestartPolicy, mounts []docker.HostMount) (*ContainerDeployResult, error) {
 	client, err := docker.NewClientFromEnv()
 	if err != nil {
 		return nil, err
@@ -170,6 +191,7 @@ func DeployDockerContainer(imageName, containerName string) (*ContainerDeployRes
 		ExposedPorts: map[docker.Port]struct{}{
 			"8080/tcp": {},
 		},
+		Labels: labels,
 	}
 
 	ip, err := findAvailableIP(DEFAULT_DEPLOY_PORT)
@@ -186,18 +208,24 @@ func DeployDockerContainer(imageName, containerName string) (*ContainerDeployRes
 				},
 			},
 		},
+		RestartPolicy: restartPolicy,
+		Mounts:        mounts,
 	}
 
+	_, createSpan := tracer.Start(ctx, "docker create")
 	container, err := client.CreateContainer(docker.CreateContainerOptions{
 		Name:       containerName,
 		Config:     &containerConfig,
 		HostConfig: &hostConfig,
 	})
+	tracing.End(createSpan, err)
 	if err != nil {
 		return nil, err
 	}
 
+	_, startSpan := tracer.Start(ctx, "docker start")
 	err = client.StartContainer(container.ID, nil)
+	tracing.End(startSpan, err)
 	if err != nil {
 		return nil, err
 	}
@@ -246,17 +274,49 @@ type BuildAndDeployOpts struct {
 	Entrypoint   string
 	Logger       *logger.Logger
 	Db           *gorm.DB
+	Gateway      *gateway.Gateway
+	// Carries the trace of the upload, the spans of the deploy are its
+	// children. Background if nil.
+	Context context.Context
+	// Called when the deploy is done, with the revision if one was created and
+	// the error that stopped the deploy if any
+	OnFinish func(rev *models.Revision, err error)
 }
 
 func BuildAndDeploy(opts BuildAndDeployOpts) {
 	logger := opts.Logger
 	db := opts.Db
-	startTime := time.Now().UnixMilli()
+	startTime := time.Now()
 	logger.Info("Building and deploying app", "file", opts.FilePath)
 
+	var err error
+	var createdRevision *models.Revision
+	if opts.OnFinish != nil {
+		defer func() {
+			opts.OnFinish(createdRevision, err)
+		}()
+	}
+	metrics.BuildsInProgress.Inc()
+	defer func() {
+		metrics.BuildsInProgress.Dec()
+		metrics.ObserveDeploy(time.Since(startTime), err)
+	}()
+
+	ctx := opts.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, span := tracer.Start(ctx, "deploy", trace.WithAttributes(
+		attribute.String("bulut.namespace_id", opts.NamespaceId),
+		attribute.String("bulut.deployment_id", opts.DeploymentId.String()),
+	))
+	defer func() {
+		tracing.End(span, err)
+	}()
+
 	dockerName := fmt.Sprintf("bulut-%s-%s", opts.NamespaceId, opts.DeploymentId)
 	tempDir := filepath.Join(os.TempDir(), dockerName)
-	err := os.MkdirAll(tempDir, os.ModePerm)
+	err = os.MkdirAll(tempDir, os.ModePerm)
 	if err != nil {
 		logger.Error(err, "Failed to create temporary directory")
 		return
@@ -270,55 +330,66 @@ func BuildAndDeploy(opts BuildAndDeployOpts) {
 		}
 	}(tempDir, opts.FilePath)
 
+	buildStart := time.Now()
+	_, extractSpan := tracer.Start(ctx, "extract archive")
 	err = ExtractZip(opts.FilePath, tempDir)
+	tracing.End(extractSpan, err)
 	if err != nil {
 		logger.Error(err, "Failed to extract archive")
+		metrics.ObserveBuild(time.Since(buildStart), err)
 		return
 	}
 
-	if err := CreateDockerfileIfNotPresent(tempDir, opts.Entrypoint); err != nil {
+	if err = CreateDockerfileIfNotPresent(tempDir, opts.Entrypoint); err != nil {
 		logger.Error(err, "Failed to create Dockerfile")
+		metrics.ObserveBuild(time.Since(buildStart), err)
 		return
 	}
 
-	buildResult, err := BuildDockerImage(dockerName, tempDir)
+	buildResult, err := BuildDockerImage(ctx, dockerName, tempDir)
+	metrics.ObserveBuild(time.Since(buildStart), err)
 	if err != nil {
 		logger.Error(err, "Failed to build Docker image")
 		return
 	}
 	var currentDeployment models.Deployment
-	err = db.Where("id = ?", opts.DeploymentId).First(&currentDeployment).Error
+	err = db.Preload("Namespace").Where("id = ?", opts.DeploymentId).First(&currentDeployment).Error
 	if err != nil {
 		logger.Error(err, "Failed to get current deployment")
 		return
 	}
-	_, err = revision.CreateRevision(db, opts.DeploymentId, dockerName, buildResult.ImageTag, buildResult.ImageID)
-
-	// Delete old container
-	if currentDeployment.ContainerID != "" {
-		if err := DeleteContainer(currentDeployment.ContainerID); err != nil {
-			logger.Error(err, "Failed to delete old container")
-			return
-		}
-	}
-
-	imageName := fmt.Sprintf("%s:%s", dockerName, buildResult.ImageTag)
-	deployResult, err := DeployDockerContainer(imageName, dockerName)
+	rev, err := revision.CreateRevision(db, opts.DeploymentId, dockerName, buildResult.ImageTag, buildResult.ImageID)
 	if err != nil {
-		logger.Error(err, "Failed to deploy Docker container")
+		logger.Error(err, "Failed to create revision")
 		return
 	}
-	logger.Info("Successfully deployed app", "ip", deployResult.IP, "port", DEFAULT_DEPLOY_PORT, "time_ms", time.Now().UnixMilli()-startTime)
-
-	// Update deployment
-	currentDeployment.ContainerID = deployResult.ContainerID
-	if err := db.Save(&currentDeployment).Error; err != nil {
-		logger.Error(err, "Failed to update deployment")
+	createdRevision = &rev
+
+	err = RollReplicas(ReplicaOpts{
+		Db:      db,
+		Logger:  logger,
+		Gateway: opts.Gateway,
+		Context: ctx,
+	}, currentDeployment, rev)
+	if errors.Is(err, ErrDeploymentDeleted) {
+		// Deleting the deployment removed the revisions and images it knew of
+		logger.Info("Deployment was deleted during the build, discarding the image")
+		if err := db.Delete(&rev).Error; err != nil {
+			logger.Error(err, "Failed to delete revision of deleted deployment")
+		}
+		removeImages(ReplicaOpts{Logger: logger}, []models.Revision{rev})
+		return
+	}
+	if err != nil {
+		logger.Error(err, "Failed to roll out replicas")
 		return
 	}
+	logger.Info("Successfully deployed app", "replicas", currentDeployment.Replicas, "time_ms", time.Since(startTime).Milliseconds())
 }
 
-func DeleteContainer(containerID string) error {
+// DeleteContainer force-removes a container, with its anonymous volumes if
+// removeVolumes is set.
+func DeleteContainer(containerID string, removeVolumes bool) error {
 	client, err := docker.NewClientFromEnv()
 	if err != nil {
 		return err
@@ -326,7 +397,7 @@ func DeleteContainer(containerID string) error {
 
 	err = client.RemoveContainer(docker.RemoveContainerOptions{
 		ID:            containerID,
-		RemoveVolumes: true, // TOOD: Possible data loss
+		RemoveVolumes: removeVolumes,
 		Force:         true,
 	})
 	if err != nil {
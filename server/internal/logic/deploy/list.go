@@ -0,0 +1,177 @@
+package deploy
+
+import (
+	"bulut-server/pkg/orm/models"
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"strings"
+)
+
+// Summarized status of a deployment, from the state of its replicas
+const (
+	StatusRunning      = "running"
+	StatusDegraded     = "degraded"
+	StatusStopped      = "stopped"
+	StatusCrashLooping = "crash-looping"
+	StatusNotDeployed  = "not-deployed"
+	StatusUnknown      = "unknown"
+)
+
+// State of a replica whose container no longer exists in Docker
+const stateMissing = "missing"
+
+func ValidStatus(status string) bool {
+	switch status {
+	case StatusRunning, StatusDegraded, StatusStopped, StatusCrashLooping, StatusNotDeployed, StatusUnknown:
+		return true
+	}
+	return false
+}
+
+type ListFilter struct {
+	NamespaceID uuid.UUID
+	// Only deployments with this in their name, ignoring case
+	Name string
+	// Only deployments with this summarized status
+	Status  string
+	Page    int
+	PerPage int
+}
+
+type ReplicaStatus struct {
+	ContainerID string
+	Address     string
+	// Docker state of the container, like running or exited
+	State string
+}
+
+// DeploymentStatus is a deployment with the live state of its replicas.
+type DeploymentStatus struct {
+	models.Deployment
+	Status        string
+	ReplicaStates []ReplicaStatus
+}
+
+// ListDeployments returns a page of deployments of a namespace matching the
+// filter, ordered by name, and the total number of matching deployments. The
+// state of the replicas is read from Docker with client, it is unknown if the
+// client is nil.
+func ListDeployments(db *gorm.DB, client *docker.Client, filter ListFilter) ([]DeploymentStatus, int64, error) {
+	query := db.Model(&models.Deployment{}).Where("namespace_id = ?", filter.NamespaceID)
+	if filter.Name != "" {
+		query = query.Where("LOWER(name) LIKE ?", "%"+strings.ToLower(filter.Name)+"%")
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// The status only exists in Docker, so all deployments are inspected and
+	// the page is taken after filtering
+	paged := filter.Status == ""
+	query = query.Preload("Containers").Order("name asc")
+	if paged {
+		query = query.Offset((filter.Page - 1) * filter.PerPage).Limit(filter.PerPage)
+	}
+	var deployments []models.Deployment
+	if err := query.Find(&deployments).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// Without Docker the deployments are still listed, with an unknown status
+	states := containerStates(client)
+	statuses := make([]DeploymentStatus, 0, len(deployments))
+	for _, deployment := range deployments {
+		status := deploymentStatus(states, deployment)
+		if paged || status.Status == filter.Status {
+			statuses = append(statuses, status)
+		}
+	}
+	if paged {
+		return statuses, total, nil
+	}
+
+	total = int64(len(statuses))
+	start := (filter.Page - 1) * filter.PerPage
+	if start > len(statuses) {
+		start = len(statuses)
+	}
+	end := start + filter.PerPage
+	if end > len(statuses) {
+		end = len(statuses)
+	}
+	return statuses[start:end], total, nil
+}
+
+func deploymentStatus(states map[string]string, deployment models.Deployment) DeploymentStatus {
+	status := DeploymentStatus{
+		Deployment:    deployment,
+		ReplicaStates: make([]ReplicaStatus, 0, len(deployment.Containers)),
+	}
+	running := 0
+	unknown := false
+	for _, container := range deployment.Containers {
+		state := containerState(states, container.ContainerID)
+		switch state {
+		case "running":
+			running++
+		case StatusUnknown:
+			unknown = true
+		}
+		status.ReplicaStates = append(status.ReplicaStates, ReplicaStatus{
+			ContainerID: container.ContainerID,
+			Address:     container.Address,
+			State:       state,
+		})
+	}
+
+	switch {
+	case deployment.CrashLooping:
+		status.Status = StatusCrashLooping
+	case len(deployment.Containers) == 0:
+		status.Status = StatusNotDeployed
+	case unknown:
+		status.Status = StatusUnknown
+	case running == 0:
+		status.Status = StatusStopped
+	case running < len(deployment.Containers):
+		status.Status = StatusDegraded
+	default:
+		status.Status = StatusRunning
+	}
+	return status
+}
+
+// containerStates returns the Docker state of all replica containers by their
+// ID, nil if Docker is not available. A single list is cheaper than inspecting
+// every replica of every deployment.
+func containerStates(client *docker.Client) map[string]string {
+	if client == nil {
+		return nil
+	}
+	containers, err := client.ListContainers(docker.ListContainersOptions{
+		All:     true,
+		Filters: map[string][]string{"label": {DeploymentLabel}},
+	})
+	if err != nil {
+		return nil
+	}
+	states := make(map[string]string, len(containers))
+	for _, container := range containers {
+		states[container.ID] = container.State
+	}
+	return states
+}
+
+func containerState(states map[string]string, containerID string) string {
+	if states == nil {
+		return StatusUnknown
+	}
+	state, ok := states[containerID]
+	if !ok {
+		return stateMissing
+	}
+	return state
+}
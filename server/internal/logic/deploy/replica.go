@@ -0,0 +1,325 @@
+package deploy
+
+import (
+	"bulut-server/internal/gateway"
+	"bulut-server/internal/logic/revision"
+	"bulut-server/internal/tracing"
+	"bulut-server/pkg/logger"
+	"bulut-server/pkg/orm/common"
+	"bulut-server/pkg/orm/models"
+	"context"
+	"errors"
+	"fmt"
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"math/rand"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const MaxReplicas = 10
+
+// Labels set on replica containers
+const (
+	DeploymentLabel = "bulut.deployment"
+	RevisionLabel   = "bulut.revision"
+)
+
+// How long a new replica has to start listening before a rollout is aborted
+const replicaReadyTimeout = 30 * time.Second
+
+var deploymentLocks sync.Map
+
+// ErrDeploymentDeleted is returned by operations that took the lock of a
+// deployment after it was deleted.
+var ErrDeploymentDeleted = errors.New("deployment was deleted")
+
+// lockDeployment serializes rollouts and scaling of a single deployment.
+func lockDeployment(id uuid.UUID) func() {
+	mu, _ := deploymentLocks.LoadOrStore(id, &sync.Mutex{})
+	mu.(*sync.Mutex).Lock()
+	return mu.(*sync.Mutex).Unlock
+}
+
+// reloadDeployment reads the deployment again after its lock was taken, it
+// returns ErrDeploymentDeleted if it no longer exists.
+func reloadDeployment(opts ReplicaOpts, deployment *models.Deployment) error {
+	err := opts.Db.Preload("Namespace").First(deployment, "id = ?", deployment.ID).Error
+	if errors.Is(err, common.ErrNotFound) {
+		return ErrDeploymentDeleted
+	}
+	return err
+}
+
+type ReplicaOpts struct {
+	Db      *gorm.DB
+	Logger  *logger.Logger
+	Gateway *gateway.Gateway
+	// Carries the trace the spans of the operation belong to, background if
+	// nil
+	Context context.Context
+}
+
+func (opts ReplicaOpts) ctx() context.Context {
+	if opts.Context == nil {
+		return context.Background()
+	}
+	return opts.Context
+}
+
+// FindContainers returns the running replicas of a deployment, oldest first.
+func FindContainers(db *gorm.DB, deployment models.Deployment) ([]models.Container, error) {
+	var containers []models.Container
+	result := db.Where("deployment_id = ?", deployment.ID).Order("created_at asc").Find(&containers)
+	return containers, result.Error
+}
+
+// StartReplica starts a container for the given revision and waits until it
+// accepts connections.
+func StartReplica(opts ReplicaOpts, deployment models.Deployment, rev models.Revision) (container models.Container, err error) {
+	ctx, span := tracer.Start(opts.ctx(), "start replica")
+	defer func() {
+		tracing.End(span, err)
+	}()
+
+	imageName := fmt.Sprintf("%s:%s", rev.ImageName, rev.ImageTag)
+	containerName := fmt.Sprintf("%s-%08x", rev.ImageName, rand.Uint32())
+
+	labels := map[string]string{
+		DeploymentLabel: deployment.ID.String(),
+		RevisionLabel:   rev.ID.String(),
+	}
+	volumes, err := FindVolumes(opts.Db, deployment)
+	if err != nil {
+		return models.Container{}, err
+	}
+	client, err := docker.NewClientFromEnv()
+	if err != nil {
+		return models.Container{}, err
+	}
+	mounts, err := volumeMounts(client, deployment, volumes)
+	if err != nil {
+		return models.Container{}, err
+	}
+
+	deployResult, err := DeployDockerContainer(ctx, imageName, containerName, labels, restartPolicy(deployment), mounts)
+	if err != nil {
+		return models.Container{}, err
+	}
+	address := net.JoinHostPort(deployResult.IP, fmt.Sprint(DEFAULT_DEPLOY_PORT))
+
+	_, waitSpan := tracer.Start(ctx, "wait for replica")
+	err = waitForReplica(address, replicaReadyTimeout)
+	tracing.End(waitSpan, err)
+	if err != nil {
+		if err := DeleteContainer(deployResult.ContainerID, true); err != nil {
+			opts.Logger.Error(err, "Failed to delete unready container", "container", deployResult.ContainerID)
+		}
+		return models.Container{}, err
+	}
+
+	container = models.Container{
+		DeploymentID: deployment.ID,
+		RevisionID:   rev.ID,
+		ContainerID:  deployResult.ContainerID,
+		Address:      address,
+	}
+	if err := opts.Db.Create(&container).Error; err != nil {
+		return models.Container{}, err
+	}
+	return container, nil
+}
+
+// StopReplica takes a replica out of the gateway before removing its container.
+func StopReplica(opts ReplicaOpts, deployment models.Deployment, container models.Container) error {
+	if err := opts.Db.Delete(&container).Error; err != nil {
+		return err
+	}
+	if err := SyncGateway(opts, deployment); err != nil {
+		return err
+	}
+	return DeleteContainer(container.ContainerID, true)
+}
+
+// RollReplicas replaces the replicas of a deployment with containers of the
+// given revision one by one, so that at least one replica is serving at all
+// times. If a new replica fails to start, the rollout stops and the remaining
+// old replicas are kept.
+func RollReplicas(opts ReplicaOpts, deployment models.Deployment, rev models.Revision) (err error) {
+	ctx, span := tracer.Start(opts.ctx(), "roll out replicas", trace.WithAttributes(attribute.Int("bulut.replicas", deployment.Replicas)))
+	defer func() {
+		tracing.End(span, err)
+	}()
+	opts.Context = ctx
+
+	defer lockDeployment(deployment.ID)()
+
+	// The deployment may have been deleted or scaled while waiting for the
+	// lock, like during the build of an upload
+	if err := reloadDeployment(opts, &deployment); err != nil {
+		return err
+	}
+
+	old, err := FindContainers(opts.Db, deployment)
+	if err != nil {
+		return err
+	}
+
+	replicas := deployment.Replicas
+	if replicas < 1 {
+		replicas = 1
+	}
+	for i := 0; i < replicas; i++ {
+		if _, err := StartReplica(opts, deployment, rev); err != nil {
+			return err
+		}
+		if err := SyncGateway(opts, deployment); err != nil {
+			return err
+		}
+		if i < len(old) {
+			if err := StopReplica(opts, deployment, old[i]); err != nil {
+				return err
+			}
+		}
+	}
+	for i := replicas; i < len(old); i++ {
+		if err := StopReplica(opts, deployment, old[i]); err != nil {
+			return err
+		}
+	}
+
+	// Crash tracking starts over with the new revision
+	err = opts.Db.Model(&deployment).Omit(clause.Associations).Updates(map[string]interface{}{
+		"restart_count": 0,
+		"crash_looping": false,
+	}).Error
+	if err != nil {
+		return err
+	}
+
+	// Containers from before replicas were supported are not tracked in the
+	// containers table
+	if deployment.ContainerID != "" {
+		if err := DeleteContainer(deployment.ContainerID, true); err != nil {
+			opts.Logger.Error(err, "Failed to delete legacy container", "container", deployment.ContainerID)
+		}
+		deployment.ContainerID = ""
+		if err := opts.Db.Model(&deployment).Omit(clause.Associations).Update("container_id", "").Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Scale starts or stops replicas of the latest revision until the deployment
+// has the given number of replicas.
+func Scale(opts ReplicaOpts, deployment models.Deployment, replicas int, balancer gateway.Strategy) (models.Deployment, error) {
+	defer lockDeployment(deployment.ID)()
+	if err := reloadDeployment(opts, &deployment); err != nil {
+		return deployment, err
+	}
+
+	deployment.Replicas = replicas
+	if balancer != "" {
+		deployment.Balancer = string(balancer)
+	}
+	err := opts.Db.Model(&deployment).Omit(clause.Associations).Updates(map[string]interface{}{
+		"replicas": deployment.Replicas,
+		"balancer": deployment.Balancer,
+	}).Error
+	if err != nil {
+		return deployment, err
+	}
+
+	containers, err := FindContainers(opts.Db, deployment)
+	if err != nil {
+		return deployment, err
+	}
+
+	// Nothing has been deployed yet, the next deploy will use the new count
+	if len(containers) == 0 {
+		return deployment, nil
+	}
+
+	rev, err := revision.GetLatestRevision(opts.Db, deployment.ID)
+	if err != nil {
+		return deployment, err
+	}
+	for i := len(containers); i < replicas; i++ {
+		if _, err := StartReplica(opts, deployment, rev); err != nil {
+			return deployment, err
+		}
+	}
+	// Stop the oldest replicas first
+	for i := 0; i < len(containers)-replicas; i++ {
+		if err := StopReplica(opts, deployment, containers[i]); err != nil {
+			return deployment, err
+		}
+	}
+
+	return deployment, SyncGateway(opts, deployment)
+}
+
+// SyncGateway points the gateway route of a deployment to its current replicas.
+func SyncGateway(opts ReplicaOpts, deployment models.Deployment) error {
+	if opts.Gateway == nil {
+		return nil
+	}
+	if deployment.Namespace.Name == "" {
+		if err := opts.Db.Preload("Namespace").First(&deployment, "id = ?", deployment.ID).Error; err != nil {
+			return err
+		}
+	}
+
+	containers, err := FindContainers(opts.Db, deployment)
+	if err != nil {
+		return err
+	}
+	addresses := make([]string, 0, len(containers))
+	for _, c := range containers {
+		addresses = append(addresses, c.Address)
+	}
+
+	hostname := opts.Gateway.Hostname(deployment.Namespace.Name, deployment.Name)
+	opts.Gateway.SetRoute(hostname, gateway.Strategy(deployment.Balancer), addresses)
+	return nil
+}
+
+// RestoreGatewayRoutes registers all deployments in the gateway, used on startup.
+func RestoreGatewayRoutes(opts ReplicaOpts) error {
+	var deployments []models.Deployment
+	if err := opts.Db.Preload("Namespace").Find(&deployments).Error; err != nil {
+		return err
+	}
+	for _, deployment := range deployments {
+		if err := SyncGateway(opts, deployment); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// waitForReplica polls the replica over HTTP. A plain TCP dial is not enough
+// because Docker's port proxy accepts connections before the app listens.
+func waitForReplica(address string, timeout time.Duration) error {
+	client := &http.Client{Timeout: 2 * time.Second}
+	deadline := time.Now().Add(timeout)
+	for {
+		resp, err := client.Get("http://" + address + "/")
+		if err == nil {
+			_ = resp.Body.Close()
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("replica at %s did not become ready in %s: %w", address, timeout, err)
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
@@ -0,0 +1,74 @@
+package deploy
+
+import (
+	"bulut-server/pkg/orm/models"
+	"fmt"
+	docker "github.com/fsouza/go-dockerclient"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	RestartNever     = "no"
+	RestartOnFailure = "on-failure"
+	RestartAlways    = "always"
+)
+
+func ValidRestartPolicy(policy string) bool {
+	return policy == RestartNever || policy == RestartOnFailure || policy == RestartAlways
+}
+
+func restartPolicy(deployment models.Deployment) docker.RestartPolicy {
+	switch deployment.RestartPolicy {
+	case RestartAlways:
+		return docker.AlwaysRestart()
+	case RestartOnFailure:
+		return docker.RestartOnFailure(deployment.RestartMaxRetries)
+	default:
+		return docker.NeverRestart()
+	}
+}
+
+// SetRestartPolicy stores the restart policy and applies it to the running
+// replicas of the deployment.
+func SetRestartPolicy(opts ReplicaOpts, deployment models.Deployment, policy string, maxRetries int) (models.Deployment, error) {
+	if !ValidRestartPolicy(policy) {
+		return deployment, fmt.Errorf("invalid restart policy: %s", policy)
+	}
+	if policy != RestartOnFailure {
+		maxRetries = 0
+	}
+
+	defer lockDeployment(deployment.ID)()
+	if err := reloadDeployment(opts, &deployment); err != nil {
+		return deployment, err
+	}
+
+	deployment.RestartPolicy = policy
+	deployment.RestartMaxRetries = maxRetries
+	err := opts.Db.Model(&deployment).Omit(clause.Associations).Updates(map[string]interface{}{
+		"restart_policy":      deployment.RestartPolicy,
+		"restart_max_retries": deployment.RestartMaxRetries,
+	}).Error
+	if err != nil {
+		return deployment, err
+	}
+
+	containers, err := FindContainers(opts.Db, deployment)
+	if err != nil {
+		return deployment, err
+	}
+	client, err := docker.NewClientFromEnv()
+	if err != nil {
+		return deployment, err
+	}
+	for _, c := range containers {
+		err := client.UpdateContainer(c.ContainerID, docker.UpdateContainerOptions{
+			RestartPolicy: restartPolicy(deployment),
+		})
+		if err != nil {
+			return deployment, err
+		}
+	}
+
+	return deployment, nil
+}
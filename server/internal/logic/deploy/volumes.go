@@ -0,0 +1,309 @@
+package deploy
+
+import (
+	"bulut-server/internal/logic/revision"
+	"bulut-server/internal/logic/snapshot"
+	"bulut-server/pkg/orm/models"
+	"errors"
+	"fmt"
+	docker "github.com/fsouza/go-dockerclient"
+	"gorm.io/gorm"
+	"io"
+	"path"
+	"regexp"
+)
+
+// Path the helper container mounts a volume at
+const helperMountPath = "/volume"
+
+var volumeNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+
+var (
+	ErrVolumeNotFound = errors.New("volume not found")
+	ErrInvalidVolume  = errors.New("invalid volume")
+)
+
+// ValidateVolumeName checks that a volume name is usable in the name of a
+// Docker volume and in snapshot IDs.
+func ValidateVolumeName(name string) error {
+	if !volumeNamePattern.MatchString(name) {
+		return fmt.Errorf("%w: name %q must be lowercase letters, digits and dashes", ErrInvalidVolume, name)
+	}
+	return nil
+}
+
+// ValidateVolume checks the name and the mount path of a volume.
+func ValidateVolume(volume models.Volume) error {
+	if err := ValidateVolumeName(volume.Name); err != nil {
+		return err
+	}
+	if !path.IsAbs(volume.Path) || path.Clean(volume.Path) != volume.Path || volume.Path == "/" {
+		return fmt.Errorf("%w: mount path %q of %s must be a clean absolute path other than /", ErrInvalidVolume, volume.Path, volume.Name)
+	}
+	return nil
+}
+
+// DockerVolumeName is the name of the Docker volume of a deployment volume,
+// unique across namespaces.
+func DockerVolumeName(deployment models.Deployment, name string) string {
+	return fmt.Sprintf("bulut-%s-%s", deployment.ID, name)
+}
+
+func FindVolumes(db *gorm.DB, deployment models.Deployment) ([]models.Volume, error) {
+	volumes := []models.Volume{}
+	result := db.Where("deployment_id = ?", deployment.ID).Order("name asc").Find(&volumes)
+	return volumes, result.Error
+}
+
+// SetVolumes replaces the volumes mounted into the replicas of a deployment
+// and rolls out the latest revision with them. Docker volumes of removed
+// volumes are kept with their data until the deployment is deleted.
+func SetVolumes(opts ReplicaOpts, deployment models.Deployment, volumes []models.Volume) error {
+	seen := make(map[string]bool, len(volumes))
+	for i := range volumes {
+		if err := ValidateVolume(volumes[i]); err != nil {
+			return err
+		}
+		if seen[volumes[i].Name] {
+			return fmt.Errorf("%w: %s is listed twice", ErrInvalidVolume, volumes[i].Name)
+		}
+		seen[volumes[i].Name] = true
+		volumes[i].DeploymentID = deployment.ID
+	}
+
+	err := opts.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("deployment_id = ?", deployment.ID).Delete(&models.Volume{}).Error; err != nil {
+			return err
+		}
+		if len(volumes) == 0 {
+			return nil
+		}
+		return tx.Create(&volumes).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	containers, err := FindContainers(opts.Db, deployment)
+	if err != nil || len(containers) == 0 {
+		return err
+	}
+	rev, err := revision.GetLatestRevision(opts.Db, deployment.ID)
+	if err != nil {
+		return err
+	}
+	return RollReplicas(opts, deployment, rev)
+}
+
+// volumeMounts creates the Docker volumes of a deployment that do not exist
+// yet and returns their mounts.
+func volumeMounts(client *docker.Client, deployment models.Deployment, volumes []models.Volume) ([]docker.HostMount, error) {
+	mounts := make([]docker.HostMount, 0, len(volumes))
+	for _, volume := range volumes {
+		name, err := ensureDockerVolume(client, deployment, volume.Name)
+		if err != nil {
+			return nil, err
+		}
+		mounts = append(mounts, docker.HostMount{Type: "volume", Source: name, Target: volume.Path})
+	}
+	return mounts, nil
+}
+
+// ensureDockerVolume creates the Docker volume of a deployment volume unless
+// it exists and returns its name.
+func ensureDockerVolume(client *docker.Client, deployment models.Deployment, name string) (string, error) {
+	dockerName := DockerVolumeName(deployment, name)
+	_, err := client.InspectVolume(dockerName)
+	if err == nil {
+		return dockerName, nil
+	}
+	if !errors.Is(err, docker.ErrNoSuchVolume) {
+		return "", err
+	}
+	_, err = client.CreateVolume(docker.CreateVolumeOptions{
+		Name:   dockerName,
+		Labels: map[string]string{DeploymentLabel: deployment.ID.String()},
+	})
+	return dockerName, err
+}
+
+// removeDockerVolumes removes the Docker volumes created for a deployment.
+func removeDockerVolumes(deployment models.Deployment) error {
+	client, err := docker.NewClientFromEnv()
+	if err != nil {
+		return err
+	}
+	volumes, err := client.ListVolumes(docker.ListVolumesOptions{
+		Filters: map[string][]string{"label": {DeploymentLabel + "=" + deployment.ID.String()}},
+	})
+	if err != nil {
+		return err
+	}
+	for _, volume := range volumes {
+		if err := client.RemoveVolumeWithOptions(docker.RemoveVolumeOptions{Name: volume.Name}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SnapshotVolume stores the content of a volume of the deployment, read
+// through a helper container while the replicas keep running.
+func SnapshotVolume(opts ReplicaOpts, store *snapshot.Store, deployment models.Deployment, name string) (snapshot.Snapshot, error) {
+	if err := ValidateVolumeName(name); err != nil {
+		return snapshot.Snapshot{}, err
+	}
+	client, err := docker.NewClientFromEnv()
+	if err != nil {
+		return snapshot.Snapshot{}, err
+	}
+	dockerName := DockerVolumeName(deployment, name)
+	if _, err := client.InspectVolume(dockerName); err != nil {
+		if errors.Is(err, docker.ErrNoSuchVolume) {
+			return snapshot.Snapshot{}, ErrVolumeNotFound
+		}
+		return snapshot.Snapshot{}, err
+	}
+
+	helper, err := createHelper(client, store.HelperImage(), dockerName, true, nil)
+	if err != nil {
+		return snapshot.Snapshot{}, err
+	}
+	defer removeHelper(opts, client, helper)
+
+	return store.Create(deployment.ID.String(), name, func(w io.Writer) error {
+		// The archive has the content under volume/, which restore extracts
+		// into the mount of its helper
+		return client.DownloadFromContainer(helper, docker.DownloadFromContainerOptions{
+			Path:         helperMountPath,
+			OutputStream: w,
+		})
+	})
+}
+
+// RestoreSnapshot replaces the content of a volume of the deployment with a
+// snapshot, the volume of the snapshot if target is empty. The volume is
+// created if it does not exist. The replicas are stopped during the restore
+// and started again afterwards, with the volumes the deployment mounts.
+func RestoreSnapshot(opts ReplicaOpts, store *snapshot.Store, deployment models.Deployment, id, target string) (snap snapshot.Snapshot, err error) {
+	snap, archive, err := store.Open(deployment.ID.String(), id)
+	if err != nil {
+		return snap, err
+	}
+	defer archive.Close()
+	if target == "" {
+		target = snap.Volume
+	}
+	if err := ValidateVolumeName(target); err != nil {
+		return snap, err
+	}
+
+	client, err := docker.NewClientFromEnv()
+	if err != nil {
+		return snap, err
+	}
+
+	defer lockDeployment(deployment.ID)()
+	if err := reloadDeployment(opts, &deployment); err != nil {
+		return snap, err
+	}
+
+	containers, err := FindContainers(opts.Db, deployment)
+	if err != nil {
+		return snap, err
+	}
+	for _, container := range containers {
+		if err := StopReplica(opts, deployment, container); err != nil {
+			return snap, err
+		}
+	}
+	if len(containers) > 0 {
+		defer func() {
+			if startErr := startReplicas(opts, deployment); err == nil {
+				err = startErr
+			}
+		}()
+	}
+
+	dockerName, err := ensureDockerVolume(client, deployment, target)
+	if err != nil {
+		return snap, err
+	}
+	// Empties the volume, hidden files included, before the archive is
+	// extracted into it
+	emptyCmd := []string{"sh", "-c", "rm -rf " + helperMountPath + "/..?* " + helperMountPath + "/.[!.]* " + helperMountPath + "/*"}
+	helper, err := createHelper(client, store.HelperImage(), dockerName, false, emptyCmd)
+	if err != nil {
+		return snap, err
+	}
+	defer removeHelper(opts, client, helper)
+
+	if err := client.StartContainer(helper, nil); err != nil {
+		return snap, err
+	}
+	exitCode, err := client.WaitContainer(helper)
+	if err != nil {
+		return snap, err
+	}
+	if exitCode != 0 {
+		return snap, fmt.Errorf("failed to empty volume %s, helper exited with %d", target, exitCode)
+	}
+	err = client.UploadToContainer(helper, docker.UploadToContainerOptions{
+		Path:        path.Dir(helperMountPath),
+		InputStream: archive,
+	})
+	return snap, err
+}
+
+// startReplicas starts the replicas of the latest revision after they were
+// stopped, the caller holds the lock of the deployment.
+func startReplicas(opts ReplicaOpts, deployment models.Deployment) error {
+	rev, err := revision.GetLatestRevision(opts.Db, deployment.ID)
+	if err != nil {
+		return err
+	}
+	replicas := deployment.Replicas
+	if replicas < 1 {
+		replicas = 1
+	}
+	for i := 0; i < replicas; i++ {
+		if _, err := StartReplica(opts, deployment, rev); err != nil {
+			return err
+		}
+	}
+	return SyncGateway(opts, deployment)
+}
+
+// createHelper creates a container that mounts a volume at helperMountPath,
+// pulling its image if needed. It is only started if it has to run cmd.
+func createHelper(client *docker.Client, image, volume string, readOnly bool, cmd []string) (string, error) {
+	if _, err := client.InspectImage(image); err != nil {
+		if !errors.Is(err, docker.ErrNoSuchImage) {
+			return "", err
+		}
+		repository, tag := docker.ParseRepositoryTag(image)
+		if err := client.PullImage(docker.PullImageOptions{Repository: repository, Tag: tag}, docker.AuthConfiguration{}); err != nil {
+			return "", err
+		}
+	}
+	container, err := client.CreateContainer(docker.CreateContainerOptions{
+		Config: &docker.Config{
+			Image: image,
+			Cmd:   cmd,
+		},
+		HostConfig: &docker.HostConfig{
+			Mounts: []docker.HostMount{{Type: "volume", Source: volume, Target: helperMountPath, ReadOnly: readOnly}},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	return container.ID, nil
+}
+
+func removeHelper(opts ReplicaOpts, client *docker.Client, id string) {
+	err := client.RemoveContainer(docker.RemoveContainerOptions{ID: id, Force: true})
+	if err != nil {
+		opts.Logger.Error(err, "Failed to remove helper container", "container", id)
+	}
+}
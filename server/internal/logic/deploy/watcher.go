@@ -0,0 +1,275 @@
+package deploy
+
+import (
+	"bulut-server/pkg/orm/models"
+	"context"
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// A deployment is crash-looping when its replicas die crashLoopThreshold times
+// within crashLoopWindow. Its containers then stop being restarted by Docker
+// and are started again after a backoff that doubles every time.
+const (
+	crashLoopThreshold  = 5
+	crashLoopWindow     = 5 * time.Minute
+	crashLoopMinBackoff = 10 * time.Second
+	crashLoopMaxBackoff = 5 * time.Minute
+)
+
+// Delay between attempts to listen to the events of Docker
+const (
+	watchMinRetryDelay = time.Second
+	watchMaxRetryDelay = time.Minute
+)
+
+// Watcher follows Docker container events to record exits and restarts of
+// replicas and to detect crash loops.
+type Watcher struct {
+	opts   ReplicaOpts
+	client *docker.Client
+
+	mu        sync.Mutex
+	failures  map[uuid.UUID][]time.Time
+	backoff   map[uuid.UUID]time.Duration
+	died      map[string]bool
+	oomKilled map[string]bool
+	// Containers the watcher stops itself, their die event is not an exit
+	stopping map[string]bool
+}
+
+func NewWatcher(opts ReplicaOpts, client *docker.Client) *Watcher {
+	return &Watcher{
+		opts:      opts,
+		client:    client,
+		failures:  make(map[uuid.UUID][]time.Time),
+		backoff:   make(map[uuid.UUID]time.Duration),
+		died:      make(map[string]bool),
+		oomKilled: make(map[string]bool),
+		stopping:  make(map[string]bool),
+	}
+}
+
+// Start follows the events in the background until ctx is done. While Docker
+// refuses the event listener it is retried with a delay that doubles every
+// time, so a Docker daemon that starts after the server is still watched.
+func (w *Watcher) Start(ctx context.Context) {
+	go func() {
+		delay := watchMinRetryDelay
+		for {
+			err := w.listen()
+			if err == nil {
+				return
+			}
+			w.opts.Logger.Error(err, "Failed to watch docker events", "retry_in", delay.String())
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(delay):
+			}
+			delay *= 2
+			if delay > watchMaxRetryDelay {
+				delay = watchMaxRetryDelay
+			}
+		}
+	}()
+}
+
+func (w *Watcher) listen() error {
+	events := make(chan *docker.APIEvents)
+	if err := w.client.AddEventListener(events); err != nil {
+		return err
+	}
+	go func() {
+		for event := range events {
+			if event.Type != "container" {
+				continue
+			}
+			if _, ok := event.Actor.Attributes[DeploymentLabel]; !ok {
+				continue
+			}
+			w.handle(event)
+		}
+	}()
+	return nil
+}
+
+func (w *Watcher) handle(event *docker.APIEvents) {
+	// The container is gone, its row may already be too
+	if event.Action == "destroy" {
+		w.mu.Lock()
+		delete(w.died, event.Actor.ID)
+		delete(w.oomKilled, event.Actor.ID)
+		delete(w.stopping, event.Actor.ID)
+		w.mu.Unlock()
+		return
+	}
+
+	// Containers that are not tracked anymore were removed on purpose
+	var container models.Container
+	err := w.opts.Db.Where("container_id = ?", event.Actor.ID).First(&container).Error
+	if err != nil {
+		return
+	}
+
+	switch event.Action {
+	case "oom":
+		w.mu.Lock()
+		w.oomKilled[container.ContainerID] = true
+		w.mu.Unlock()
+	case "die":
+		w.handleDie(container, event)
+	case "start":
+		w.handleStart(container)
+	}
+}
+
+func (w *Watcher) handleDie(container models.Container, event *docker.APIEvents) {
+	logger := w.opts.Logger
+	exitCode, _ := strconv.Atoi(event.Actor.Attributes["exitCode"])
+	now := time.Now()
+
+	w.mu.Lock()
+	if w.stopping[container.ContainerID] {
+		delete(w.stopping, container.ContainerID)
+		delete(w.oomKilled, container.ContainerID)
+		w.mu.Unlock()
+		return
+	}
+	reason := "exited"
+	if w.oomKilled[container.ContainerID] {
+		reason = "oom"
+		delete(w.oomKilled, container.ContainerID)
+	}
+	w.died[container.ContainerID] = true
+	failures := append(w.failures[container.DeploymentID], now)
+	for len(failures) > 0 && now.Sub(failures[0]) > crashLoopWindow {
+		failures = failures[1:]
+	}
+	w.failures[container.DeploymentID] = failures
+	w.mu.Unlock()
+
+	logger.Info("Replica exited", "deployment", container.DeploymentID, "container", container.ContainerID, "exit_code", exitCode, "reason", reason)
+
+	var deployment models.Deployment
+	if err := w.opts.Db.First(&deployment, "id = ?", container.DeploymentID).Error; err != nil {
+		logger.Error(err, "Failed to get deployment of exited replica")
+		return
+	}
+	err := w.opts.Db.Model(&deployment).Updates(map[string]interface{}{
+		"last_exit_code":   exitCode,
+		"last_exit_reason": reason,
+		"last_exit_at":     now,
+	}).Error
+	if err != nil {
+		logger.Error(err, "Failed to record replica exit")
+		return
+	}
+
+	if deployment.RestartPolicy == RestartNever || len(failures) < crashLoopThreshold {
+		return
+	}
+	w.backOff(deployment, container)
+}
+
+// backOff stops Docker from restarting the container and starts it again
+// after the backoff delay of the deployment.
+func (w *Watcher) backOff(deployment models.Deployment, container models.Container) {
+	logger := w.opts.Logger
+
+	w.mu.Lock()
+	delay := w.backoff[deployment.ID] * 2
+	if delay < crashLoopMinBackoff {
+		delay = crashLoopMinBackoff
+	}
+	if delay > crashLoopMaxBackoff {
+		delay = crashLoopMaxBackoff
+	}
+	w.backoff[deployment.ID] = delay
+	w.failures[deployment.ID] = nil
+	w.mu.Unlock()
+
+	logger.Info("Deployment is crash-looping, backing off", "deployment", deployment.ID, "container", container.ContainerID, "backoff", delay.String())
+	if err := w.opts.Db.Model(&deployment).Update("crash_looping", true).Error; err != nil {
+		logger.Error(err, "Failed to flag deployment as crash-looping")
+	}
+
+	err := w.client.UpdateContainer(container.ContainerID, docker.UpdateContainerOptions{
+		RestartPolicy: docker.NeverRestart(),
+	})
+	if err != nil {
+		logger.Error(err, "Failed to pause restarts of crash-looping container")
+	}
+	// Docker may already be restarting the container. Stopping it is not a
+	// crash, so its die event is ignored.
+	w.mu.Lock()
+	w.stopping[container.ContainerID] = true
+	w.mu.Unlock()
+	if err := w.client.StopContainer(container.ContainerID, 0); err != nil {
+		// Not running, so there is no die event to ignore
+		w.mu.Lock()
+		delete(w.stopping, container.ContainerID)
+		w.mu.Unlock()
+	}
+
+	time.AfterFunc(delay, func() {
+		if err := w.opts.Db.First(&container, "id = ?", container.ID).Error; err != nil {
+			// Replaced or scaled down in the meantime
+			return
+		}
+		if err := w.opts.Db.First(&deployment, "id = ?", deployment.ID).Error; err != nil {
+			return
+		}
+		err := w.client.UpdateContainer(container.ContainerID, docker.UpdateContainerOptions{
+			RestartPolicy: restartPolicy(deployment),
+		})
+		if err != nil {
+			logger.Error(err, "Failed to restore restart policy of container", "container", container.ContainerID)
+		}
+		if err := w.client.StartContainer(container.ContainerID, nil); err != nil {
+			logger.Error(err, "Failed to restart container after backoff", "container", container.ContainerID)
+		}
+	})
+}
+
+func (w *Watcher) handleStart(container models.Container) {
+	startedAt := time.Now()
+
+	w.mu.Lock()
+	restarted := w.died[container.ContainerID]
+	delete(w.died, container.ContainerID)
+	w.mu.Unlock()
+
+	if restarted {
+		err := w.opts.Db.Model(&models.Deployment{}).
+			Where("id = ?", container.DeploymentID).
+			Update("restart_count", gorm.Expr("restart_count + 1")).Error
+		if err != nil {
+			w.opts.Logger.Error(err, "Failed to record replica restart")
+		}
+	}
+
+	// The deployment recovered if nothing failed for a whole window
+	time.AfterFunc(crashLoopWindow, func() {
+		w.mu.Lock()
+		failures := w.failures[container.DeploymentID]
+		stable := len(failures) == 0 || failures[len(failures)-1].Before(startedAt)
+		if stable {
+			delete(w.backoff, container.DeploymentID)
+		}
+		w.mu.Unlock()
+		if !stable {
+			return
+		}
+		err := w.opts.Db.Model(&models.Deployment{}).
+			Where("id = ? AND crash_looping = ?", container.DeploymentID, true).
+			Update("crash_looping", false).Error
+		if err != nil {
+			w.opts.Logger.Error(err, "Failed to clear crash-looping flag")
+		}
+	})
+}
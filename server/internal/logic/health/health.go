@@ -0,0 +1,107 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	docker "github.com/fsouza/go-dockerclient"
+	"gorm.io/gorm"
+	"sync"
+	"time"
+)
+
+// Status of a check and of all checks together
+const (
+	StatusOK      = "ok"
+	StatusFailing = "failing"
+)
+
+// Limit of a single check, so a hanging dependency fails instead of hanging
+// the probe
+const checkTimeout = 2 * time.Second
+
+// Check is a dependency the server needs to serve requests.
+type Check struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// Result is the outcome of a check.
+type Result struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	// Why the check fails, only shown to admins
+	Error string `json:"error,omitempty"`
+	// Time the check took in milliseconds, only shown to admins
+	DurationMs int64 `json:"duration_ms,omitempty"`
+}
+
+// Run runs the checks concurrently and reports whether all of them pass. The
+// results are in the order of the checks.
+func Run(ctx context.Context, checks []Check) ([]Result, bool) {
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+			defer cancel()
+
+			start := time.Now()
+			err := check.Check(ctx)
+			results[i] = Result{Name: check.Name, Status: StatusOK, DurationMs: time.Since(start).Milliseconds()}
+			if err != nil {
+				results[i].Status = StatusFailing
+				results[i].Error = err.Error()
+			}
+		}(i, check)
+	}
+	wg.Wait()
+
+	for _, result := range results {
+		if result.Status != StatusOK {
+			return results, false
+		}
+	}
+	return results, true
+}
+
+// Database checks that the database answers.
+func Database(db *gorm.DB) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		if db == nil {
+			return errors.New("not connected")
+		}
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	}
+}
+
+// Docker checks that the Docker daemon answers.
+func Docker(client *docker.Client) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		if client == nil {
+			return errors.New("not connected")
+		}
+		return client.PingWithContext(ctx)
+	}
+}
+
+// DiskSpace checks that the file system of dir has at least minFree bytes
+// available, where uploads are saved and built.
+func DiskSpace(dir string, minFree uint64) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		free, err := freeSpace(dir)
+		if err != nil {
+			return err
+		}
+		if free < minFree {
+			return fmt.Errorf("%d MB free in %s, at least %d MB needed", free>>20, dir, minFree>>20)
+		}
+		return nil
+	}
+}
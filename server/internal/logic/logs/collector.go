@@ -0,0 +1,116 @@
+package logs
+
+import (
+	"bulut-server/pkg/logger"
+	"bulut-server/pkg/orm/models"
+	"context"
+	docker "github.com/fsouza/go-dockerclient"
+	"gorm.io/gorm"
+	"sync"
+	"time"
+)
+
+// How often the collector looks for replicas it does not follow yet
+const collectInterval = 2 * time.Second
+
+// Collector follows the output of every replica and writes it to the store,
+// so logs outlive the containers that are replaced on each deploy.
+type Collector struct {
+	db     *gorm.DB
+	logger *logger.Logger
+	client *docker.Client
+	store  *Store
+
+	mu        sync.Mutex
+	following map[string]bool
+	lastSeen  map[string]time.Time
+}
+
+func NewCollector(db *gorm.DB, logger *logger.Logger, client *docker.Client, store *Store) *Collector {
+	return &Collector{
+		db:        db,
+		logger:    logger,
+		client:    client,
+		store:     store,
+		following: make(map[string]bool),
+		lastSeen:  make(map[string]time.Time),
+	}
+}
+
+func (c *Collector) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(collectInterval)
+		defer ticker.Stop()
+		for {
+			c.collect(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (c *Collector) collect(ctx context.Context) {
+	var containers []models.Container
+	if err := c.db.Find(&containers).Error; err != nil {
+		c.logger.Error(err, "Failed to list containers for log collection")
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	tracked := make(map[string]bool, len(containers))
+	for _, container := range containers {
+		tracked[container.ContainerID] = true
+		if c.following[container.ContainerID] {
+			continue
+		}
+		c.following[container.ContainerID] = true
+		go c.follow(ctx, container)
+	}
+	for id := range c.lastSeen {
+		if !tracked[id] && !c.following[id] {
+			delete(c.lastSeen, id)
+		}
+	}
+}
+
+// follow streams the output of a container until it stops. The next collect
+// picks it up again if it is restarted.
+func (c *Collector) follow(ctx context.Context, container models.Container) {
+	deploymentID := container.DeploymentID.String()
+	revisionID := container.RevisionID.String()
+	short := ShortID(container.ContainerID)
+
+	c.mu.Lock()
+	since := c.lastSeen[container.ContainerID]
+	c.mu.Unlock()
+
+	err := Stream(ctx, c.client, []models.Container{container}, StreamOptions{
+		Tail:       "all",
+		Since:      since,
+		Timestamps: true,
+		Follow:     true,
+	}, func(line Line) error {
+		// Since has a precision of seconds, skip lines that are already stored
+		if line.Time == nil || !line.Time.After(since) {
+			return nil
+		}
+		since = *line.Time
+		line.Revision = revisionID
+		return c.store.Append(deploymentID, revisionID, short, []Line{line})
+	})
+	if err != nil {
+		if _, ok := err.(*docker.NoSuchContainer); !ok {
+			c.logger.Error(err, "Failed to collect container logs", "container", short)
+		}
+	}
+	c.store.Close(deploymentID, revisionID, short)
+
+	c.mu.Lock()
+	c.lastSeen[container.ContainerID] = since
+	delete(c.following, container.ContainerID)
+	c.mu.Unlock()
+}
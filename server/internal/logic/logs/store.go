@@ -0,0 +1,308 @@
+package logs
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Size of a single log file before a new one is started
+const segmentMaxBytes = 4 << 20
+
+type StoreConfig struct {
+	Dir string
+	// Oldest segments of a deployment are removed when its logs exceed this
+	MaxBytesPerDeployment int64
+}
+
+// Store keeps container output on disk, laid out as
+// <dir>/<deployment>/<revision>/<container>-<unix nano of first line>.log
+// with one JSON encoded Line per line.
+type Store struct {
+	config   *StoreConfig
+	mu       sync.Mutex
+	segments map[string]*segment
+}
+
+type segment struct {
+	path string
+	file *os.File
+	size int64
+}
+
+func NewStore(config *StoreConfig) (*Store, error) {
+	if err := os.MkdirAll(config.Dir, 0755); err != nil {
+		return nil, err
+	}
+	return &Store{
+		config:   config,
+		segments: make(map[string]*segment),
+	}, nil
+}
+
+// Append stores lines of a container. Lines must have Time set.
+func (s *Store) Append(deploymentID, revisionID, container string, lines []Line) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dir := filepath.Join(s.config.Dir, deploymentID, revisionID)
+	key := filepath.Join(dir, container)
+	rotated := false
+	for _, line := range lines {
+		seg := s.segments[key]
+		if seg == nil || seg.size >= segmentMaxBytes {
+			if seg != nil {
+				_ = seg.file.Close()
+			}
+			var err error
+			seg, err = openSegment(dir, container, *line.Time)
+			if err != nil {
+				return err
+			}
+			s.segments[key] = seg
+			rotated = true
+		}
+
+		data, err := json.Marshal(line)
+		if err != nil {
+			return err
+		}
+		data = append(data, '\n')
+		n, err := seg.file.Write(data)
+		seg.size += int64(n)
+		if err != nil {
+			return err
+		}
+	}
+
+	if rotated {
+		return s.enforceLimit(deploymentID)
+	}
+	return nil
+}
+
+// Close closes the open segment of a container, called when it stops.
+func (s *Store) Close(deploymentID, revisionID, container string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := filepath.Join(s.config.Dir, deploymentID, revisionID, container)
+	if seg, ok := s.segments[key]; ok {
+		_ = seg.file.Close()
+		delete(s.segments, key)
+	}
+}
+
+// Delete removes all logs of a deployment.
+func (s *Store) Delete(deploymentID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dir := filepath.Join(s.config.Dir, deploymentID)
+	for key, seg := range s.segments {
+		if strings.HasPrefix(key, dir+string(filepath.Separator)) {
+			_ = seg.file.Close()
+			delete(s.segments, key)
+		}
+	}
+	return os.RemoveAll(dir)
+}
+
+func openSegment(dir, container string, start time.Time) (*segment, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, fmt.Sprintf("%s-%d.log", container, start.UnixNano()))
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	return &segment{path: path, file: file, size: info.Size()}, nil
+}
+
+type segmentFile struct {
+	path     string
+	revision string
+	start    time.Time
+	modified time.Time
+	size     int64
+}
+
+// listSegments returns the segments of a deployment, oldest first.
+func (s *Store) listSegments(deploymentID, revisionID string) ([]segmentFile, error) {
+	pattern := filepath.Join(s.config.Dir, deploymentID, "*", "*.log")
+	if revisionID != "" {
+		pattern = filepath.Join(s.config.Dir, deploymentID, revisionID, "*.log")
+	}
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	segments := make([]segmentFile, 0, len(paths))
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), ".log")
+		i := strings.LastIndexByte(name, '-')
+		if i < 0 {
+			continue
+		}
+		nanos, err := strconv.ParseInt(name[i+1:], 10, 64)
+		if err != nil {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		segments = append(segments, segmentFile{
+			path:     path,
+			revision: filepath.Base(filepath.Dir(path)),
+			start:    time.Unix(0, nanos),
+			modified: info.ModTime(),
+			size:     info.Size(),
+		})
+	}
+	sort.Slice(segments, func(i, j int) bool {
+		return segments[i].start.Before(segments[j].start)
+	})
+	return segments, nil
+}
+
+func (s *Store) enforceLimit(deploymentID string) error {
+	if s.config.MaxBytesPerDeployment <= 0 {
+		return nil
+	}
+	segments, err := s.listSegments(deploymentID, "")
+	if err != nil {
+		return err
+	}
+	var total int64
+	for _, seg := range segments {
+		total += seg.size
+	}
+	for _, seg := range segments {
+		if total <= s.config.MaxBytesPerDeployment {
+			break
+		}
+		// Never remove a segment that is still being written
+		open := false
+		for _, o := range s.segments {
+			if o.path == seg.path {
+				open = true
+			}
+		}
+		if open {
+			continue
+		}
+		if err := os.Remove(seg.path); err != nil {
+			return err
+		}
+		total -= seg.size
+	}
+	return nil
+}
+
+type Query struct {
+	RevisionID string
+	Since      time.Time
+	Until      time.Time
+	Contains   string
+	Regex      *regexp.Regexp
+	// Only the last Limit matching lines are returned
+	Limit int
+}
+
+// Search returns the stored lines of a deployment matching the query, ordered
+// by time.
+func (s *Store) Search(deploymentID string, q Query) ([]Line, error) {
+	s.mu.Lock()
+	for _, seg := range s.segments {
+		_ = seg.file.Sync()
+	}
+	s.mu.Unlock()
+
+	segments, err := s.listSegments(deploymentID, q.RevisionID)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []Line
+	trim := func() {
+		sort.SliceStable(matches, func(i, j int) bool {
+			return matches[i].Time.Before(*matches[j].Time)
+		})
+		if q.Limit > 0 && len(matches) > q.Limit {
+			matches = matches[len(matches)-q.Limit:]
+		}
+	}
+
+	for _, seg := range segments {
+		if !q.Until.IsZero() && seg.start.After(q.Until) {
+			continue
+		}
+		if !q.Since.IsZero() && seg.modified.Before(q.Since) {
+			continue
+		}
+		err := scanSegment(seg.path, func(line Line) {
+			if line.Time == nil {
+				return
+			}
+			if !q.Since.IsZero() && line.Time.Before(q.Since) {
+				return
+			}
+			if !q.Until.IsZero() && line.Time.After(q.Until) {
+				return
+			}
+			if q.Contains != "" && !strings.Contains(line.Text, q.Contains) {
+				return
+			}
+			if q.Regex != nil && !q.Regex.MatchString(line.Text) {
+				return
+			}
+			matches = append(matches, line)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if q.Limit > 0 && len(matches) > 2*q.Limit {
+			trim()
+		}
+	}
+	trim()
+	return matches, nil
+}
+
+func scanSegment(path string, fn func(Line)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// Removed by the size limit in the meantime
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), segmentMaxBytes)
+	for scanner.Scan() {
+		var line Line
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			// Partially written line
+			continue
+		}
+		fn(line)
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,157 @@
+package logs
+
+import (
+	"bulut-server/pkg/orm/models"
+	"bytes"
+	"context"
+	docker "github.com/fsouza/go-dockerclient"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	Stdout = "stdout"
+	Stderr = "stderr"
+)
+
+// Line is a single line of container output.
+type Line struct {
+	Container string     `json:"container"`
+	Revision  string     `json:"revision,omitempty"`
+	Stream    string     `json:"stream"`
+	Time      *time.Time `json:"time,omitempty"`
+	Text      string     `json:"text"`
+}
+
+type StreamOptions struct {
+	Tail       string
+	Since      time.Time
+	Timestamps bool
+	Follow     bool
+}
+
+// ShortID returns the container ID in the form Docker prints it.
+func ShortID(containerID string) string {
+	if len(containerID) > 12 {
+		return containerID[:12]
+	}
+	return containerID
+}
+
+// Stream reads the logs of all containers and calls fn for every line. Lines
+// of different containers are interleaved in the order they arrive. fn is
+// never called concurrently.
+func Stream(ctx context.Context, client *docker.Client, containers []models.Container, opts StreamOptions, fn func(Line) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	lines := make(chan Line)
+	errs := make(chan error, len(containers))
+	var wg sync.WaitGroup
+	for _, container := range containers {
+		wg.Add(1)
+		go func(container models.Container) {
+			defer wg.Done()
+			stdout := newLineWriter(ctx, ShortID(container.ContainerID), Stdout, opts.Timestamps, lines)
+			stderr := newLineWriter(ctx, ShortID(container.ContainerID), Stderr, opts.Timestamps, lines)
+			logsOpts := docker.LogsOptions{
+				Context:      ctx,
+				Container:    container.ContainerID,
+				OutputStream: stdout,
+				ErrorStream:  stderr,
+				Tail:         opts.Tail,
+				Follow:       opts.Follow,
+				Stdout:       true,
+				Stderr:       true,
+				Timestamps:   opts.Timestamps,
+			}
+			if !opts.Since.IsZero() {
+				logsOpts.Since = opts.Since.Unix()
+			}
+			err := client.Logs(logsOpts)
+			stdout.flush()
+			stderr.flush()
+			if err != nil && ctx.Err() == nil {
+				errs <- err
+			}
+		}(container)
+	}
+	go func() {
+		wg.Wait()
+		close(lines)
+	}()
+
+	for line := range lines {
+		if err := fn(line); err != nil {
+			return err
+		}
+	}
+	close(errs)
+	return <-errs
+}
+
+// lineWriter splits container output into lines.
+type lineWriter struct {
+	ctx        context.Context
+	container  string
+	stream     string
+	timestamps bool
+	lines      chan<- Line
+	buf        bytes.Buffer
+}
+
+func newLineWriter(ctx context.Context, container, stream string, timestamps bool, lines chan<- Line) *lineWriter {
+	return &lineWriter{
+		ctx:        ctx,
+		container:  container,
+		stream:     stream,
+		timestamps: timestamps,
+		lines:      lines,
+	}
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.buf.Write(p)
+	for {
+		i := bytes.IndexByte(w.buf.Bytes(), '\n')
+		if i < 0 {
+			break
+		}
+		text := string(w.buf.Next(i + 1))
+		if err := w.emit(strings.TrimRight(text, "\r\n")); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+func (w *lineWriter) flush() {
+	if w.buf.Len() > 0 {
+		_ = w.emit(w.buf.String())
+		w.buf.Reset()
+	}
+}
+
+func (w *lineWriter) emit(text string) error {
+	line := Line{
+		Container: w.container,
+		Stream:    w.stream,
+		Text:      text,
+	}
+	// Docker prefixes every line with an RFC3339Nano timestamp
+	if w.timestamps {
+		if ts, rest, ok := strings.Cut(text, " "); ok {
+			if t, err := time.Parse(time.RFC3339Nano, ts); err == nil {
+				line.Time = &t
+				line.Text = rest
+			}
+		}
+	}
+	select {
+	case w.lines <- line:
+		return nil
+	case <-w.ctx.Done():
+		return w.ctx.Err()
+	}
+}
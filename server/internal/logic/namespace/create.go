@@ -1,12 +1,25 @@
 package namespace
 
 import (
+	"bulut-server/internal/logic/auth"
 	"bulut-server/pkg/orm/models"
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
-func CreateNamespace(db *gorm.DB, name string) (models.Namespace, error) {
+// CreateNamespace creates a namespace owned by the given user. Namespaces
+// created with the bootstrap API key pass uuid.Nil and have no owner.
+func CreateNamespace(db *gorm.DB, name string, ownerId uuid.UUID) (models.Namespace, error) {
 	namespace := models.Namespace{Name: name}
-	result := db.Create(&namespace)
-	return namespace, result.Error
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&namespace).Error; err != nil {
+			return err
+		}
+		if ownerId == uuid.Nil {
+			return nil
+		}
+		_, err := SetMember(tx, namespace.ID, ownerId, auth.RoleOwner)
+		return err
+	})
+	return namespace, err
 }
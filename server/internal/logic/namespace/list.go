@@ -0,0 +1,77 @@
+package namespace
+
+import (
+	"bulut-server/pkg/orm/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"strings"
+)
+
+type ListFilter struct {
+	// Only namespaces with this in their name, ignoring case
+	Name string
+	// Only namespaces the user is a member of, all namespaces if uuid.Nil
+	MemberID uuid.UUID
+	Page     int
+	PerPage  int
+}
+
+// NamespaceSummary is a namespace with the number of its deployments.
+type NamespaceSummary struct {
+	models.Namespace
+	DeploymentCount int64
+}
+
+// ListNamespaces returns a page of namespaces matching the filter, ordered by
+// name, and the total number of matching namespaces.
+func ListNamespaces(db *gorm.DB, filter ListFilter) ([]NamespaceSummary, int64, error) {
+	query := db.Model(&models.Namespace{})
+	if filter.Name != "" {
+		query = query.Where("LOWER(name) LIKE ?", "%"+strings.ToLower(filter.Name)+"%")
+	}
+	if filter.MemberID != uuid.Nil {
+		members := db.Model(&models.NamespaceMember{}).Select("namespace_id").Where("user_id = ?", filter.MemberID)
+		query = query.Where("id IN (?)", members)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var namespaces []models.Namespace
+	result := query.Order("name asc").
+		Offset((filter.Page - 1) * filter.PerPage).
+		Limit(filter.PerPage).
+		Find(&namespaces)
+	if result.Error != nil {
+		return nil, 0, result.Error
+	}
+
+	ids := make([]uuid.UUID, 0, len(namespaces))
+	for _, ns := range namespaces {
+		ids = append(ids, ns.ID)
+	}
+	var counts []struct {
+		NamespaceID uuid.UUID
+		Count       int64
+	}
+	err := db.Model(&models.Deployment{}).
+		Select("namespace_id, COUNT(*) AS count").
+		Where("namespace_id IN ?", ids).
+		Group("namespace_id").
+		Scan(&counts).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	countByID := make(map[uuid.UUID]int64, len(counts))
+	for _, c := range counts {
+		countByID[c.NamespaceID] = c.Count
+	}
+
+	summaries := make([]NamespaceSummary, 0, len(namespaces))
+	for _, ns := range namespaces {
+		summaries = append(summaries, NamespaceSummary{Namespace: ns, DeploymentCount: countByID[ns.ID]})
+	}
+	return summaries, total, nil
+}
@@ -0,0 +1,81 @@
+package namespace
+
+import (
+	"bulut-server/internal/logic/auth"
+	"bulut-server/pkg/orm/common"
+	"bulut-server/pkg/orm/models"
+	"errors"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var ErrLastOwner = errors.New("namespace must keep at least one owner")
+
+// FindMemberRole returns the role of the user in the namespace, or an empty
+// role if the user is not a member.
+func FindMemberRole(db *gorm.DB, namespaceId, userId uuid.UUID) (auth.Role, error) {
+	var member models.NamespaceMember
+	result := db.Where("namespace_id = ? AND user_id = ?", namespaceId, userId).First(&member)
+	if result.Error != nil {
+		if errors.Is(result.Error, common.ErrNotFound) {
+			return "", nil
+		}
+		return "", result.Error
+	}
+	return auth.Role(member.Role), nil
+}
+
+func ListMembers(db *gorm.DB, namespaceId uuid.UUID) ([]models.NamespaceMember, error) {
+	var members []models.NamespaceMember
+	result := db.Preload("User").Where("namespace_id = ?", namespaceId).Order("created_at asc").Find(&members)
+	return members, result.Error
+}
+
+// SetMember adds the user to the namespace or changes its role.
+func SetMember(db *gorm.DB, namespaceId, userId uuid.UUID, role auth.Role) (models.NamespaceMember, error) {
+	var member models.NamespaceMember
+	err := db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where(models.NamespaceMember{NamespaceID: namespaceId, UserID: userId}).FirstOrInit(&member).Error
+		if err != nil {
+			return err
+		}
+		if auth.Role(member.Role) == auth.RoleOwner && role != auth.RoleOwner {
+			if err := checkNotLastOwner(tx, namespaceId); err != nil {
+				return err
+			}
+		}
+		member.Role = string(role)
+		return tx.Save(&member).Error
+	})
+	return member, err
+}
+
+func RemoveMember(db *gorm.DB, namespaceId, userId uuid.UUID) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var member models.NamespaceMember
+		err := tx.Where("namespace_id = ? AND user_id = ?", namespaceId, userId).First(&member).Error
+		if err != nil {
+			return err
+		}
+		if auth.Role(member.Role) == auth.RoleOwner {
+			if err := checkNotLastOwner(tx, namespaceId); err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&member).Error
+	})
+}
+
+func checkNotLastOwner(db *gorm.DB, namespaceId uuid.UUID) error {
+	var owners int64
+	err := db.Model(&models.NamespaceMember{}).
+		Where("namespace_id = ? AND role = ?", namespaceId, auth.RoleOwner).
+		Count(&owners).Error
+	if err != nil {
+		return err
+	}
+	if owners <= 1 {
+		return ErrLastOwner
+	}
+	return nil
+}
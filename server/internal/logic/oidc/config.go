@@ -0,0 +1,99 @@
+package oidc
+
+import (
+	"bulut-server/internal/logic/auth"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type Config struct {
+	Issuer   string
+	ClientID string
+	Scopes   []string
+	// Claims the user name and groups are read from
+	UsernameClaim string
+	GroupsClaim   string
+	// Scope every user gets, rules can only grant more
+	DefaultScope auth.Scope
+	Rules        []Rule
+	// Lifetime of the bulut tokens issued after login
+	SessionTTL time.Duration
+	RefreshTTL time.Duration
+}
+
+// Rule grants members of a group either a token scope or a role in a namespace.
+type Rule struct {
+	Group     string
+	Scope     auth.Scope
+	Namespace string
+	Role      auth.Role
+}
+
+// ParseRules parses comma separated rules of the form group:scope or
+// group:namespace/role, for example "ops:admin,web-team:web/deployer".
+func ParseRules(rules string) ([]Rule, error) {
+	var parsed []Rule
+	for _, rule := range strings.Split(rules, ",") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+		i := strings.LastIndexByte(rule, ':')
+		if i <= 0 || i == len(rule)-1 {
+			return nil, fmt.Errorf("invalid rule %q, expected group:scope or group:namespace/role", rule)
+		}
+		group, grant := rule[:i], rule[i+1:]
+
+		if namespace, role, ok := strings.Cut(grant, "/"); ok {
+			if namespace == "" || !auth.Role(role).Valid() {
+				return nil, fmt.Errorf("invalid namespace role in rule %q", rule)
+			}
+			parsed = append(parsed, Rule{Group: group, Namespace: namespace, Role: auth.Role(role)})
+			continue
+		}
+		if !auth.Scope(grant).Valid() {
+			return nil, fmt.Errorf("invalid scope in rule %q", rule)
+		}
+		parsed = append(parsed, Rule{Group: group, Scope: auth.Scope(grant)})
+	}
+	return parsed, nil
+}
+
+// Grants returns the token scopes and namespace roles for a user in the groups.
+// A role comes with the scope needed to act with it. When a group matches
+// several roles in a namespace, the highest one wins.
+func (c *Config) Grants(groups []string) ([]auth.Scope, map[string]auth.Role) {
+	member := make(map[string]bool, len(groups))
+	for _, g := range groups {
+		member[g] = true
+	}
+
+	var scopes []auth.Scope
+	addScope := func(scope auth.Scope) {
+		for _, s := range scopes {
+			if s == scope {
+				return
+			}
+		}
+		scopes = append(scopes, scope)
+	}
+	if c.DefaultScope != "" {
+		addScope(c.DefaultScope)
+	}
+	roles := make(map[string]auth.Role)
+	for _, rule := range c.Rules {
+		if !member[rule.Group] {
+			continue
+		}
+		if rule.Scope != "" {
+			addScope(rule.Scope)
+			continue
+		}
+		addScope(rule.Role.Scope())
+		if current, ok := roles[rule.Namespace]; !ok || rule.Role.Includes(current) {
+			roles[rule.Namespace] = rule.Role
+		}
+	}
+	return scopes, roles
+}
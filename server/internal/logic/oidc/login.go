@@ -0,0 +1,95 @@
+package oidc
+
+import (
+	"bulut-server/internal/logic/auth"
+	"bulut-server/internal/logic/namespace"
+	"bulut-server/pkg/orm/common"
+	"bulut-server/pkg/orm/models"
+	"errors"
+	"gorm.io/gorm"
+)
+
+// Login finds or creates the user of the claims, applies the namespace roles
+// of its groups and returns the scopes its session gets.
+func Login(db *gorm.DB, config *Config, claims Claims) (models.User, []auth.Scope, error) {
+	scopes, roles := config.Grants(claims.Groups)
+
+	var user models.User
+	err := db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("subject = ?", claims.Subject).First(&user).Error
+		if errors.Is(err, common.ErrNotFound) {
+			user, err = createUser(tx, claims)
+		}
+		if err != nil {
+			return err
+		}
+		return applyRoles(tx, user, roles)
+	})
+	return user, scopes, err
+}
+
+// Refresh exchanges a refresh token for a new session and revokes the session
+// it was issued with. The grants are computed again from the groups the user
+// had at login, so changed rules apply without logging in again.
+func Refresh(db *gorm.DB, config *Config, refreshToken string) (auth.Session, error) {
+	var session auth.Session
+	err := db.Transaction(func(tx *gorm.DB) error {
+		refresh, err := auth.RedeemRefreshToken(tx, refreshToken)
+		if err != nil {
+			return err
+		}
+		scopes, roles := config.Grants(refresh.Groups)
+		if err := applyRoles(tx, refresh.User, roles); err != nil {
+			return err
+		}
+		session, err = auth.CreateSession(tx, refresh.User, scopes, refresh.Groups, config.SessionTTL, config.RefreshTTL)
+		return err
+	})
+	return session, err
+}
+
+// applyRoles makes the user a member of the namespaces with the roles of its
+// groups. Namespaces that do not exist are skipped.
+func applyRoles(tx *gorm.DB, user models.User, roles map[string]auth.Role) error {
+	for name, role := range roles {
+		ns, err := namespace.FindNamespaceByName(tx, name)
+		if errors.Is(err, common.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		// Roles from groups only grant access, they never demote
+		current, err := namespace.FindMemberRole(tx, ns.ID, user.ID)
+		if err != nil {
+			return err
+		}
+		if current != "" && current.Includes(role) {
+			continue
+		}
+		if _, err := namespace.SetMember(tx, ns.ID, user.ID, role); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createUser names the user after the username claim. Local users are never
+// taken over, on a name clash the subject is appended.
+func createUser(db *gorm.DB, claims Claims) (models.User, error) {
+	subject := claims.Subject
+	user := models.User{
+		Name:    claims.Username,
+		Subject: &subject,
+	}
+
+	var count int64
+	if err := db.Model(&models.User{}).Where("name = ?", user.Name).Count(&count).Error; err != nil {
+		return user, err
+	}
+	if count > 0 {
+		user.Name = claims.Username + "-" + subject
+	}
+
+	return user, db.Create(&user).Error
+}
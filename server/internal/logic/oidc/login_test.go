@@ -0,0 +1,222 @@
+package oidc
+
+import (
+	"bulut-server/internal/logic/auth"
+	"bulut-server/internal/logic/namespace"
+	"bulut-server/internal/logic/oidc/oidctest"
+	"bulut-server/pkg/orm/common"
+	"bulut-server/pkg/orm/migrations"
+	"bulut-server/pkg/orm/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"gorm.io/gorm"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := common.ConnectDB(&common.DatabaseConfig{
+		Driver: common.DriverSQLite,
+		DBPath: filepath.Join(t.TempDir(), "bulut.db"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrations.Up(db); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// postForm posts to an endpoint of the issuer and decodes the JSON response.
+func postForm(t *testing.T, endpoint string, form url.Values) (int, map[string]interface{}) {
+	t.Helper()
+	resp, err := http.PostForm(endpoint, form)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var body map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, body
+}
+
+// deviceLogin goes through the device flow like the CLI does and returns the
+// ID token of the user.
+func deviceLogin(t *testing.T, issuer *oidctest.Issuer, provider *Provider, user oidctest.User) string {
+	t.Helper()
+	discovery, err := provider.Discover(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status, device := postForm(t, discovery.DeviceAuthorizationEndpoint, url.Values{"client_id": {issuer.ClientID}})
+	if status != http.StatusOK {
+		t.Fatalf("device authorization failed: %v", device)
+	}
+	poll := url.Values{
+		"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+		"device_code": {device["device_code"].(string)},
+		"client_id":   {issuer.ClientID},
+	}
+
+	_, pending := postForm(t, discovery.TokenEndpoint, poll)
+	if pending["error"] != "authorization_pending" {
+		t.Fatalf("expected authorization_pending before approval, got %v", pending)
+	}
+	if !issuer.Approve(device["user_code"].(string), user) {
+		t.Fatal("user code was not found")
+	}
+	status, token := postForm(t, discovery.TokenEndpoint, poll)
+	if status != http.StatusOK {
+		t.Fatalf("token request failed: %v", token)
+	}
+	return token["id_token"].(string)
+}
+
+func TestLoginAndRefresh(t *testing.T) {
+	issuer, err := oidctest.NewIssuer("bulut")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer issuer.Close()
+
+	rules, err := ParseRules("web-team:web/deployer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &Config{
+		Issuer:        issuer.URL,
+		ClientID:      issuer.ClientID,
+		UsernameClaim: "preferred_username",
+		GroupsClaim:   "groups",
+		DefaultScope:  auth.ScopeRead,
+		Rules:         rules,
+		SessionTTL:    time.Hour,
+		RefreshTTL:    24 * time.Hour,
+	}
+	provider := NewProvider(config, issuer.Client())
+	db := newTestDB(t)
+
+	owner, err := auth.FindOrCreateUser(db, "owner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ns, err := namespace.CreateNamespace(db, "web", owner.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	alice := oidctest.User{Subject: "sub-alice", Username: "alice", Groups: []string{"web-team"}}
+	claims, err := provider.Verify(context.Background(), deviceLogin(t, issuer, provider, alice))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != alice.Subject || claims.Username != alice.Username || len(claims.Groups) != 1 {
+		t.Fatalf("unexpected claims %+v", claims)
+	}
+
+	user, scopes, err := Login(db, config, claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := auth.JoinScopes(scopes); got != "read,deploy" {
+		t.Fatalf("expected scopes read,deploy, got %s", got)
+	}
+	role, err := namespace.FindMemberRole(db, ns.ID, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if role != auth.RoleDeployer {
+		t.Fatalf("expected role deployer, got %q", role)
+	}
+
+	session, err := auth.CreateSession(db, user, scopes, claims.Groups, config.SessionTTL, config.RefreshTTL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	identity, err := auth.Authenticate(db, session.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !identity.CanAccessNamespace(role, auth.RoleDeployer) {
+		t.Fatal("session token cannot deploy in the namespace of its role")
+	}
+
+	// Rules changed since the login apply on refresh
+	config.Rules = nil
+	refreshed, err := Refresh(db, config, session.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refreshed.Scopes != "read" {
+		t.Fatalf("expected scopes read after refresh, got %s", refreshed.Scopes)
+	}
+	if _, err := auth.Authenticate(db, refreshed.Token); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := auth.Authenticate(db, session.Token); !errors.Is(err, auth.ErrTokenRevoked) {
+		t.Fatalf("expected the previous session token to be revoked, got %v", err)
+	}
+	if _, err := Refresh(db, config, session.RefreshToken); !errors.Is(err, auth.ErrTokenRevoked) {
+		t.Fatalf("expected a used refresh token to be rejected, got %v", err)
+	}
+}
+
+func TestVerifyRejectsInvalidTokens(t *testing.T) {
+	issuer, err := oidctest.NewIssuer("bulut")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer issuer.Close()
+	config := &Config{Issuer: issuer.URL, ClientID: "bulut", UsernameClaim: "preferred_username", GroupsClaim: "groups"}
+	provider := NewProvider(config, issuer.Client())
+	user := oidctest.User{Subject: "sub-bob", Username: "bob"}
+
+	expired, err := issuer.IDToken(user, -time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.Verify(context.Background(), expired); !errors.Is(err, ErrInvalidIDToken) {
+		t.Fatalf("expected an expired token to be rejected, got %v", err)
+	}
+
+	other := NewProvider(&Config{Issuer: issuer.URL, ClientID: "other", UsernameClaim: "preferred_username"}, issuer.Client())
+	valid, err := issuer.IDToken(user, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.Verify(context.Background(), valid); !errors.Is(err, ErrInvalidIDToken) {
+		t.Fatalf("expected a token for another audience to be rejected, got %v", err)
+	}
+}
+
+func TestCreateSessionPrunesExpiredTokens(t *testing.T) {
+	db := newTestDB(t)
+	user, err := auth.FindOrCreateUser(db, "carol")
+	if err != nil {
+		t.Fatal(err)
+	}
+	scopes := []auth.Scope{auth.ScopeRead}
+
+	if _, err := auth.CreateSession(db, user, scopes, nil, -time.Minute, -time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := auth.CreateSession(db, user, scopes, nil, time.Hour, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	var tokens, refreshTokens int64
+	db.Unscoped().Model(&models.ApiToken{}).Where("user_id = ?", user.ID).Count(&tokens)
+	db.Unscoped().Model(&models.RefreshToken{}).Where("user_id = ?", user.ID).Count(&refreshTokens)
+	if tokens != 1 || refreshTokens != 1 {
+		t.Fatalf("expected only the tokens of the live session, got %d session and %d refresh tokens", tokens, refreshTokens)
+	}
+}
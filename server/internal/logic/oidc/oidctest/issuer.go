@@ -0,0 +1,179 @@
+// Package oidctest runs a local OIDC issuer with device authorization, so the
+// SSO login can be exercised without a real identity provider.
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/golang-jwt/jwt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+)
+
+const keyID = "oidctest"
+
+// User is who approves a device login.
+type User struct {
+	Subject  string
+	Username string
+	Groups   []string
+}
+
+type Issuer struct {
+	*httptest.Server
+	ClientID string
+
+	key     *rsa.PrivateKey
+	mu      sync.Mutex
+	devices map[string]*device
+}
+
+type device struct {
+	userCode string
+	user     *User
+}
+
+func NewIssuer(clientID string) (*Issuer, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	i := &Issuer{
+		ClientID: clientID,
+		key:      key,
+		devices:  make(map[string]*device),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", i.discovery)
+	mux.HandleFunc("/keys", i.jwks)
+	mux.HandleFunc("/device", i.deviceAuthorization)
+	mux.HandleFunc("/token", i.token)
+	i.Server = httptest.NewServer(mux)
+	return i, nil
+}
+
+// Approve logs the user in on the device with the user code, as if they had
+// entered it on the verification page.
+func (i *Issuer) Approve(userCode string, user User) bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	for _, d := range i.devices {
+		if d.userCode == userCode {
+			d.user = &user
+			return true
+		}
+	}
+	return false
+}
+
+// IDToken signs an ID token for the user, valid for ttl.
+func (i *Issuer) IDToken(user User, ttl time.Duration) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":                i.URL,
+		"aud":                i.ClientID,
+		"sub":                user.Subject,
+		"preferred_username": user.Username,
+		"groups":             user.Groups,
+		"iat":                time.Now().Unix(),
+		"exp":                time.Now().Add(ttl).Unix(),
+	})
+	token.Header["kid"] = keyID
+	return token.SignedString(i.key)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func randomCode(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func (i *Issuer) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{
+		"issuer":                        i.URL,
+		"device_authorization_endpoint": i.URL + "/device",
+		"token_endpoint":                i.URL + "/token",
+		"jwks_uri":                      i.URL + "/keys",
+	})
+}
+
+func (i *Issuer) jwks(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kid": keyID,
+			"kty": "RSA",
+			"alg": "RS256",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(i.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(i.key.E)).Bytes()),
+		}},
+	})
+}
+
+func (i *Issuer) deviceAuthorization(w http.ResponseWriter, r *http.Request) {
+	if r.FormValue("client_id") != i.ClientID {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_client"})
+		return
+	}
+	deviceCode, userCode := randomCode(16), randomCode(4)
+	i.mu.Lock()
+	i.devices[deviceCode] = &device{userCode: userCode}
+	i.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"device_code":               deviceCode,
+		"user_code":                 userCode,
+		"verification_uri":          i.URL + "/activate",
+		"verification_uri_complete": i.URL + "/activate?user_code=" + userCode,
+		"expires_in":                300,
+		"interval":                  1,
+	})
+}
+
+func (i *Issuer) token(w http.ResponseWriter, r *http.Request) {
+	if r.FormValue("grant_type") != "urn:ietf:params:oauth:grant-type:device_code" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+	i.mu.Lock()
+	d, ok := i.devices[r.FormValue("device_code")]
+	var user *User
+	if ok && d.user != nil {
+		user = d.user
+		delete(i.devices, r.FormValue("device_code"))
+	}
+	i.mu.Unlock()
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "expired_token"})
+		return
+	}
+	if user == nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "authorization_pending"})
+		return
+	}
+
+	idToken, err := i.IDToken(*user, time.Hour)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": randomCode(16),
+		"token_type":   "Bearer",
+		"id_token":     idToken,
+		"expires_in":   3600,
+	})
+}
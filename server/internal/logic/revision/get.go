@@ -11,3 +11,10 @@ func GetLatestRevision(db *gorm.DB, deploymentId uuid.UUID) (models.Revision, er
 	result := db.Where("deployment_id = ?", deploymentId).Order("created_at desc").First(&revision)
 	return revision, result.Error
 }
+
+// FindRevision finds a revision of a deployment by its ID or image tag.
+func FindRevision(db *gorm.DB, deploymentId uuid.UUID, idOrTag string) (models.Revision, error) {
+	var revision models.Revision
+	result := db.Where("deployment_id = ? AND (id = ? OR image_tag = ?)", deploymentId, idOrTag, idOrTag).First(&revision)
+	return revision, result.Error
+}
@@ -0,0 +1,202 @@
+// Package snapshot keeps the snapshots of deployment volumes on disk, as gzip
+// compressed tar archives of the content of the volume.
+package snapshot
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+var ErrNotFound = errors.New("snapshot not found")
+
+// Layout of the time in snapshot IDs, sortable and without path separators
+const timeLayout = "20060102T150405.000Z"
+
+const fileSuffix = ".tar.gz"
+
+type StoreConfig struct {
+	Dir string
+	// Newest snapshots kept per volume, older ones are removed when a new one
+	// is taken. All are kept if 0.
+	Retention int
+	// Image of the helper container that reads and writes volumes, it needs a
+	// shell with rm
+	HelperImage string
+}
+
+type Snapshot struct {
+	// <volume>-<time>, unique within the deployment
+	ID        string    `json:"id"`
+	Volume    string    `json:"volume"`
+	SizeBytes int64     `json:"size_bytes"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Store keeps snapshots laid out as <dir>/<deployment>/<volume>-<time>.tar.gz
+type Store struct {
+	config *StoreConfig
+}
+
+func NewStore(config *StoreConfig) (*Store, error) {
+	if err := os.MkdirAll(config.Dir, 0755); err != nil {
+		return nil, err
+	}
+	return &Store{config: config}, nil
+}
+
+func (s *Store) HelperImage() string {
+	return s.config.HelperImage
+}
+
+// Create stores a snapshot of a volume with the tar archive written by write,
+// then removes the snapshots of the volume beyond the retention. The snapshot
+// only shows up in List once it is complete.
+func (s *Store) Create(deploymentID, volume string, write func(io.Writer) error) (Snapshot, error) {
+	dir := filepath.Join(s.config.Dir, deploymentID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return Snapshot{}, err
+	}
+	createdAt := time.Now().UTC()
+	id := volume + "-" + createdAt.Format(timeLayout)
+
+	tmp, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return Snapshot{}, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	gz := gzip.NewWriter(tmp)
+	if err := write(gz); err != nil {
+		return Snapshot{}, err
+	}
+	if err := gz.Close(); err != nil {
+		return Snapshot{}, err
+	}
+	if err := tmp.Close(); err != nil {
+		return Snapshot{}, err
+	}
+	info, err := os.Stat(tmp.Name())
+	if err != nil {
+		return Snapshot{}, err
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(dir, id+fileSuffix)); err != nil {
+		return Snapshot{}, err
+	}
+
+	if err := s.prune(deploymentID, volume); err != nil {
+		return Snapshot{}, err
+	}
+	return Snapshot{ID: id, Volume: volume, SizeBytes: info.Size(), CreatedAt: createdAt}, nil
+}
+
+// List returns the snapshots of a deployment, of a single volume unless volume
+// is empty, newest first.
+func (s *Store) List(deploymentID, volume string) ([]Snapshot, error) {
+	entries, err := os.ReadDir(filepath.Join(s.config.Dir, deploymentID))
+	if errors.Is(err, os.ErrNotExist) {
+		return []Snapshot{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := []Snapshot{}
+	for _, entry := range entries {
+		snapshot, ok := parseID(strings.TrimSuffix(entry.Name(), fileSuffix))
+		if !ok || !strings.HasSuffix(entry.Name(), fileSuffix) || (volume != "" && snapshot.Volume != volume) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		snapshot.SizeBytes = info.Size()
+		snapshots = append(snapshots, snapshot)
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].CreatedAt.After(snapshots[j].CreatedAt)
+	})
+	return snapshots, nil
+}
+
+// Open returns the snapshot with the tar archive of its content, which the
+// caller must close.
+func (s *Store) Open(deploymentID, id string) (Snapshot, io.ReadCloser, error) {
+	snapshot, ok := parseID(id)
+	if !ok {
+		return Snapshot{}, nil, ErrNotFound
+	}
+	file, err := os.Open(filepath.Join(s.config.Dir, deploymentID, id+fileSuffix))
+	if errors.Is(err, os.ErrNotExist) {
+		return Snapshot{}, nil, ErrNotFound
+	}
+	if err != nil {
+		return Snapshot{}, nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return Snapshot{}, nil, err
+	}
+	snapshot.SizeBytes = info.Size()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		_ = file.Close()
+		return Snapshot{}, nil, err
+	}
+	return snapshot, &archive{Reader: gz, file: file}, nil
+}
+
+// prune removes the oldest snapshots of a volume beyond the retention.
+func (s *Store) prune(deploymentID, volume string) error {
+	if s.config.Retention <= 0 {
+		return nil
+	}
+	snapshots, err := s.List(deploymentID, volume)
+	if err != nil {
+		return err
+	}
+	for i := s.config.Retention; i < len(snapshots); i++ {
+		path := filepath.Join(s.config.Dir, deploymentID, snapshots[i].ID+fileSuffix)
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseID splits an ID into the volume and the time it was taken at. Volume
+// names may contain dashes, the time does not.
+func parseID(id string) (Snapshot, bool) {
+	i := strings.LastIndex(id, "-")
+	if i <= 0 || strings.ContainsAny(id, `/\`) {
+		return Snapshot{}, false
+	}
+	createdAt, err := time.Parse(timeLayout, id[i+1:])
+	if err != nil {
+		return Snapshot{}, false
+	}
+	return Snapshot{ID: id, Volume: id[:i], CreatedAt: createdAt}, true
+}
+
+// archive closes the file of a snapshot with its gzip reader.
+type archive struct {
+	*gzip.Reader
+	file *os.File
+}
+
+func (a *archive) Close() error {
+	err := a.Reader.Close()
+	if closeErr := a.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
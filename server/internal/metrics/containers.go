@@ -0,0 +1,198 @@
+package metrics
+
+import (
+	"bulut-server/internal/logic/logs"
+	"bulut-server/pkg/logger"
+	"context"
+	"errors"
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/prometheus/client_golang/prometheus"
+	"gorm.io/gorm"
+	"sync"
+	"time"
+)
+
+// How often the stats of the replicas are read from Docker
+const statsInterval = 15 * time.Second
+
+// Limit of reading the stats of a replica, Docker takes a second to sample
+// the CPU usage
+const statsTimeout = 5 * time.Second
+
+var (
+	containerCPUDesc = prometheus.NewDesc(
+		"bulut_container_cpu_cores",
+		"CPU used by a replica of a deployment, in cores.",
+		[]string{"namespace", "deployment", "container"}, nil,
+	)
+	containerMemoryDesc = prometheus.NewDesc(
+		"bulut_container_memory_bytes",
+		"Memory used by a replica of a deployment, without the page cache.",
+		[]string{"namespace", "deployment", "container"}, nil,
+	)
+)
+
+// replica is a running container with the names of its deployment.
+type replica struct {
+	ContainerID string
+	Namespace   string
+	Deployment  string
+}
+
+type replicaStats struct {
+	replica
+	cpuCores    float64
+	memoryBytes float64
+}
+
+// ContainerStats reads the CPU and memory usage of every replica from Docker
+// in the background, and serves the last readings as gauges when scraped.
+type ContainerStats struct {
+	db     *gorm.DB
+	logger *logger.Logger
+	client *docker.Client
+
+	mu    sync.Mutex
+	stats []replicaStats
+}
+
+func NewContainerStats(db *gorm.DB, logger *logger.Logger, client *docker.Client) *ContainerStats {
+	return &ContainerStats{
+		db:     db,
+		logger: logger,
+		client: client,
+	}
+}
+
+// Start registers the gauges and reads the stats until ctx is done.
+func (s *ContainerStats) Start(ctx context.Context) {
+	prometheus.MustRegister(s)
+	go func() {
+		ticker := time.NewTicker(statsInterval)
+		defer ticker.Stop()
+		for {
+			s.read(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (s *ContainerStats) Describe(ch chan<- *prometheus.Desc) {
+	ch <- containerCPUDesc
+	ch <- containerMemoryDesc
+}
+
+func (s *ContainerStats) Collect(ch chan<- prometheus.Metric) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, stats := range s.stats {
+		labels := []string{stats.Namespace, stats.Deployment, logs.ShortID(stats.ContainerID)}
+		ch <- prometheus.MustNewConstMetric(containerCPUDesc, prometheus.GaugeValue, stats.cpuCores, labels...)
+		ch <- prometheus.MustNewConstMetric(containerMemoryDesc, prometheus.GaugeValue, stats.memoryBytes, labels...)
+	}
+}
+
+func (s *ContainerStats) read(ctx context.Context) {
+	var replicas []replica
+	err := s.db.Table("containers").
+		Select("containers.container_id, namespaces.name AS namespace, deployments.name AS deployment").
+		Joins("JOIN deployments ON deployments.id = containers.deployment_id").
+		Joins("JOIN namespaces ON namespaces.id = deployments.namespace_id").
+		Where("containers.deleted_at IS NULL").
+		Scan(&replicas).Error
+	if err != nil {
+		s.logger.Error(err, "Failed to list containers for metrics")
+		return
+	}
+
+	results := make([]*replicaStats, len(replicas))
+	var wg sync.WaitGroup
+	for i, r := range replicas {
+		wg.Add(1)
+		go func(i int, r replica) {
+			defer wg.Done()
+			stats, err := s.containerStats(ctx, r.ContainerID)
+			if err != nil {
+				// Replaced or stopped since it was listed
+				return
+			}
+			results[i] = &replicaStats{
+				replica:     r,
+				cpuCores:    cpuCores(stats),
+				memoryBytes: float64(memoryUsage(stats)),
+			}
+		}(i, r)
+	}
+	wg.Wait()
+
+	var stats []replicaStats
+	for _, result := range results {
+		if result != nil {
+			stats = append(stats, *result)
+		}
+	}
+	s.mu.Lock()
+	s.stats = stats
+	s.mu.Unlock()
+}
+
+// containerStats reads a single sample of the stats of a container.
+func (s *ContainerStats) containerStats(ctx context.Context, containerID string) (*docker.Stats, error) {
+	ctx, cancel := context.WithTimeout(ctx, statsTimeout)
+	defer cancel()
+
+	statsC := make(chan *docker.Stats, 1)
+	errC := make(chan error, 1)
+	go func() {
+		errC <- s.client.Stats(docker.StatsOptions{
+			ID:      containerID,
+			Stats:   statsC,
+			Stream:  false,
+			Context: ctx,
+		})
+	}()
+	// The channel is closed when Stats returns
+	stats := <-statsC
+	if err := <-errC; err != nil {
+		return nil, err
+	}
+	if stats == nil {
+		return nil, errors.New("no stats for container " + containerID)
+	}
+	return stats, nil
+}
+
+// cpuCores computes the CPU usage between the two samples of stats the way
+// docker stats does, 1 being a full core.
+func cpuCores(stats *docker.Stats) float64 {
+	cpuDelta := float64(stats.CPUStats.CPUUsage.TotalUsage) - float64(stats.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(stats.CPUStats.SystemCPUUsage) - float64(stats.PreCPUStats.SystemCPUUsage)
+	if cpuDelta <= 0 || systemDelta <= 0 {
+		return 0
+	}
+	cpus := float64(stats.CPUStats.OnlineCPUs)
+	if cpus == 0 {
+		cpus = float64(len(stats.CPUStats.CPUUsage.PercpuUsage))
+	}
+	return cpuDelta / systemDelta * cpus
+}
+
+// memoryUsage is the memory used without the page cache, which the kernel
+// reclaims when needed. Cgroup v1 reports the cache, v2 the inactive files.
+func memoryUsage(stats *docker.Stats) uint64 {
+	cache := stats.MemoryStats.Stats.TotalInactiveFile
+	if cache == 0 {
+		cache = stats.MemoryStats.Stats.InactiveFile
+	}
+	if cache == 0 {
+		cache = stats.MemoryStats.Stats.Cache
+	}
+	if cache > stats.MemoryStats.Usage {
+		return stats.MemoryStats.Usage
+	}
+	return stats.MemoryStats.Usage - cache
+}
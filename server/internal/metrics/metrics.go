@@ -0,0 +1,77 @@
+// Package metrics holds the Prometheus metrics of the server, served by the
+// web server at /metrics from the default registry.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"time"
+)
+
+// Values of the result label
+const (
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+)
+
+var (
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "bulut_http_requests_total",
+		Help: "HTTP requests of the API by method, route and status.",
+	}, []string{"method", "route", "status"})
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "bulut_http_request_duration_seconds",
+		Help:    "Time to answer HTTP requests of the API by method and route.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	UploadSize = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "bulut_upload_size_bytes",
+		Help:    "Size of uploaded build outputs.",
+		Buckets: prometheus.ExponentialBuckets(1<<20, 4, 8),
+	})
+	BuildsInProgress = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "bulut_builds_in_progress",
+		Help: "Uploads being built and rolled out.",
+	})
+
+	builds = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "bulut_builds_total",
+		Help: "Image builds by result.",
+	}, []string{"result"})
+	buildDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "bulut_build_duration_seconds",
+		Help:    "Time to extract an upload and build its image by result.",
+		Buckets: prometheus.ExponentialBuckets(1, 2, 11),
+	}, []string{"result"})
+	deploys = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "bulut_deploys_total",
+		Help: "Deploys of uploads, from the upload to the rolled out replicas, by result.",
+	}, []string{"result"})
+	deployDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "bulut_deploy_duration_seconds",
+		Help:    "Time from an upload to its rolled out replicas by result.",
+		Buckets: prometheus.ExponentialBuckets(1, 2, 11),
+	}, []string{"result"})
+)
+
+// ObserveBuild records an image build that took d and failed with err if it
+// is not nil.
+func ObserveBuild(d time.Duration, err error) {
+	builds.WithLabelValues(result(err)).Inc()
+	buildDuration.WithLabelValues(result(err)).Observe(d.Seconds())
+}
+
+// ObserveDeploy records a deploy that took d and failed with err if it is not
+// nil.
+func ObserveDeploy(d time.Duration, err error) {
+	deploys.WithLabelValues(result(err)).Inc()
+	deployDuration.WithLabelValues(result(err)).Observe(d.Seconds())
+}
+
+func result(err error) string {
+	if err != nil {
+		return ResultFailure
+	}
+	return ResultSuccess
+}
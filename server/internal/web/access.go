@@ -0,0 +1,128 @@
+package web
+
+import (
+	"bulut-server/internal/logic/auth"
+	"bulut-server/pkg/logger"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Longest request ID taken from a caller, longer ones are replaced
+const maxRequestIDLength = 128
+
+// Routes polled by probes and scrapers, only logged when they fail
+var quietRoutes = map[string]bool{
+	"/healthz": true,
+	"/readyz":  true,
+	"/metrics": true,
+}
+
+// Headers whose values are hidden in logs
+var secretHeaders = map[string]bool{
+	echo.HeaderAuthorization: true,
+	echo.HeaderCookie:        true,
+	echo.HeaderSetCookie:     true,
+}
+
+// requestIDMiddleware keeps the X-Request-ID of the caller, like the CLI or
+// a proxy in front of the server, or assigns a new one. The ID is sent back
+// with the response and errors, and logged with the request.
+func (s *Server) requestIDMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		req := c.Request()
+		id := req.Header.Get(echo.HeaderXRequestID)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+			req.Header.Set(echo.HeaderXRequestID, id)
+		}
+		c.Response().Header().Set(echo.HeaderXRequestID, id)
+		return next(c)
+	}
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		if r <= ' ' || r > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func requestID(c echo.Context) string {
+	return c.Response().Header().Get(echo.HeaderXRequestID)
+}
+
+// log returns the logger of the request, its entries have the request ID.
+func (s *Server) log(c echo.Context) *logger.Logger {
+	return s.logger.With("request_id", requestID(c))
+}
+
+// accessLogMiddleware logs every request once it is answered. Errors are
+// written here, so their status is logged, along with the headers of failed
+// requests without their secrets.
+func (s *Server) accessLogMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		start := time.Now()
+		handlerErr := next(c)
+		if handlerErr != nil {
+			c.Error(handlerErr)
+		}
+
+		req := c.Request()
+		res := c.Response()
+		route := c.Path()
+		if quietRoutes[route] && res.Status < http.StatusBadRequest {
+			return nil
+		}
+
+		keysAndValues := []interface{}{
+			"request_id", requestID(c),
+			"method", req.Method,
+			"route", route,
+			"uri", req.RequestURI,
+			"status", res.Status,
+			"latency", time.Since(start),
+			"bytes_in", req.ContentLength,
+			"bytes_out", res.Size,
+			"remote_ip", c.RealIP(),
+			"user_agent", req.UserAgent(),
+		}
+		// Set by authMiddleware on the request it passes on
+		if identity, ok := auth.FromContext(req.Context()); ok {
+			keysAndValues = append(keysAndValues, "actor", identity.UserName)
+		}
+		if handlerErr != nil {
+			keysAndValues = append(keysAndValues, "error", handlerErr.Error())
+		}
+		if res.Status >= http.StatusBadRequest {
+			keysAndValues = append(keysAndValues, "headers", redactHeaders(req.Header))
+		}
+		s.logger.Info("Request", keysAndValues...)
+		return nil
+	}
+}
+
+// redactHeaders returns the headers with the values of secretHeaders hidden.
+// The scheme of an authorization is kept, to tell a bearer token from others.
+func redactHeaders(header http.Header) map[string]string {
+	redacted := make(map[string]string, len(header))
+	for name, values := range header {
+		value := strings.Join(values, ", ")
+		if secretHeaders[name] {
+			scheme, _, found := strings.Cut(value, " ")
+			value = "[redacted]"
+			if found && name == echo.HeaderAuthorization {
+				value = scheme + " [redacted]"
+			}
+		}
+		redacted[name] = value
+	}
+	return redacted
+}
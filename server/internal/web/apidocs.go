@@ -0,0 +1,262 @@
+package web
+
+import (
+	"bulut-server/internal/logic/auth"
+	"bulut-server/internal/logic/logs"
+	"bulut-server/internal/logic/snapshot"
+	"bulut-server/pkg/orm/models"
+	"mime/multipart"
+	"net/http"
+)
+
+// UploadRequest is the form of an upload, read by uploadHandler.
+type UploadRequest struct {
+	// Zip archive of the build output
+	File *multipart.FileHeader `json:"file" validate:"required"`
+}
+
+var (
+	pageParams = []queryParam{
+		{Name: "page", Type: "integer", Description: "Page to return, starting at 1"},
+		{Name: "per_page", Type: "integer", Description: "Items per page, 50 by default and at most 500"},
+	}
+	volumesParam = queryParam{Name: "volumes", Type: "boolean", Description: "Also remove the volumes of the deployments and their containers"}
+)
+
+// apiDocs documents every route of ConfigureRoutes by method and echo path,
+// without the API prefix. Routes missing here are reported by
+// UndocumentedRoutes.
+var apiDocs = map[string]routeDoc{
+	routeKey(http.MethodGet, "/openapi.json"): {
+		Summary: "Get this OpenAPI document",
+		Tag:     "meta",
+		Public:  true,
+	},
+	routeKey(http.MethodGet, "/version"): {
+		Summary:  "Get the version of the server and the range of API versions it serves",
+		Tag:      "meta",
+		Response: VersionResponse{},
+		Public:   true,
+	},
+	routeKey(http.MethodGet, "/healthz"): {
+		Summary:  "Check that the server is alive, with the checks of its dependencies and their errors for admins",
+		Tag:      "meta",
+		Response: HealthResponse{},
+		Public:   true,
+	},
+	routeKey(http.MethodGet, "/metrics"): {
+		Summary:      "Get the metrics of the server and of the replicas in the Prometheus text format",
+		Tag:          "meta",
+		ResponseType: "text/plain",
+	},
+	routeKey(http.MethodGet, "/readyz"): {
+		Summary:  "Check that the server can serve requests, 503 while a dependency is unreachable, with the errors of the checks for admins",
+		Tag:      "meta",
+		Response: HealthResponse{},
+		Public:   true,
+	},
+
+	routeKey(http.MethodGet, "/deployment/:namespace"): {
+		Summary: "List the deployments of a namespace with the live status of their replicas",
+		Tag:     "deployments",
+		Query: append([]queryParam{
+			{Name: "name", Type: "string", Description: "Only deployments with this in their name"},
+			{Name: "status", Type: "string", Description: "Only deployments with this status", Enum: []string{
+				"running", "degraded", "stopped", "crash-looping", "not-deployed", "unknown",
+			}},
+		}, pageParams...),
+		Response: DeploymentListResponse{},
+	},
+	routeKey(http.MethodGet, "/deployment/:namespace/:deployment"): {
+		Summary:  "Get a deployment",
+		Tag:      "deployments",
+		Response: models.Deployment{},
+	},
+	routeKey(http.MethodGet, "/deployment/:namespace/:deployment/logs"): {
+		Summary: "Stream the output of the replicas of a deployment, one JSON line per output line",
+		Tag:     "logs",
+		Query: []queryParam{
+			{Name: "follow", Type: "boolean", Description: "Keep streaming new output"},
+			{Name: "since", Type: "string", Description: "Only output since a duration like 10m or an RFC3339 time"},
+			{Name: "tail", Type: "string", Description: "Number of lines from the end of each replica's output, or all"},
+			{Name: "timestamps", Type: "boolean", Description: "Include the time of each line"},
+		},
+		Response:     logs.Line{},
+		ResponseType: mimeNDJSON,
+	},
+	routeKey(http.MethodGet, "/deployment/:namespace/:deployment/logs/history"): {
+		Summary: "Search the stored logs of a deployment, including previous revisions",
+		Tag:     "logs",
+		Query: []queryParam{
+			{Name: "revision", Type: "string", Description: "Revision ID or image tag"},
+			{Name: "since", Type: "string", Description: "Duration like 10m or RFC3339 time"},
+			{Name: "until", Type: "string", Description: "Duration like 10m or RFC3339 time"},
+			{Name: "contains", Type: "string", Description: "Only lines containing this text"},
+			{Name: "regex", Type: "string", Description: "Only lines matching this regular expression"},
+			{Name: "limit", Type: "integer", Description: "Maximum number of lines"},
+		},
+		Response:     logs.Line{},
+		ResponseType: mimeNDJSON,
+	},
+	routeKey(http.MethodPost, "/deployment/"): {
+		Summary:  "Create a deployment",
+		Tag:      "deployments",
+		Request:  CreateDeploymentRequest{},
+		Response: MessageResponse{},
+		Status:   http.StatusCreated,
+	},
+	routeKey(http.MethodPut, "/deployment/upload/:namespace/:deployment"): {
+		Summary: "Upload a build output, which is built and rolled out in the background",
+		Tag:     "deployments",
+		Query: []queryParam{
+			{Name: "entrypoint", Type: "string", Description: "Entrypoint of the app in the archive"},
+		},
+		Request:     UploadRequest{},
+		RequestType: mimeMultipart,
+		Response:    UploadResponse{},
+	},
+	routeKey(http.MethodPut, "/deployment/scale/:namespace/:deployment"): {
+		Summary:  "Set the number of replicas and the load balancing of a deployment",
+		Tag:      "deployments",
+		Request:  ScaleDeploymentRequest{},
+		Response: ScaleDeploymentResponse{},
+	},
+	routeKey(http.MethodPut, "/deployment/restart-policy/:namespace/:deployment"): {
+		Summary:  "Set when the containers of a deployment are restarted",
+		Tag:      "deployments",
+		Request:  RestartPolicyRequest{},
+		Response: RestartPolicyResponse{},
+	},
+	routeKey(http.MethodDelete, "/deployment/:namespace/:deployment"): {
+		Summary:  "Delete a deployment with its containers, images and logs",
+		Tag:      "deployments",
+		Query:    []queryParam{volumesParam},
+		Response: MessageResponse{},
+	},
+	routeKey(http.MethodGet, "/deployment/:namespace/:deployment/volumes"): {
+		Summary:  "List the volumes mounted into the replicas of a deployment",
+		Tag:      "volumes",
+		Response: VolumesResponse{},
+	},
+	routeKey(http.MethodPut, "/deployment/:namespace/:deployment/volumes"): {
+		Summary:  "Set the volumes of a deployment and roll out its replicas with them",
+		Tag:      "volumes",
+		Request:  SetVolumesRequest{},
+		Response: VolumesResponse{},
+	},
+	routeKey(http.MethodGet, "/deployment/:namespace/:deployment/snapshots"): {
+		Summary:  "List the snapshots of the volumes of a deployment, newest first",
+		Tag:      "volumes",
+		Query:    []queryParam{{Name: "volume", Type: "string", Description: "Only snapshots of this volume"}},
+		Response: SnapshotListResponse{},
+	},
+	routeKey(http.MethodPost, "/deployment/:namespace/:deployment/snapshots"): {
+		Summary:  "Take a snapshot of a volume, the oldest snapshots beyond the retention are removed",
+		Tag:      "volumes",
+		Request:  CreateSnapshotRequest{},
+		Response: snapshot.Snapshot{},
+	},
+	routeKey(http.MethodPost, "/deployment/:namespace/:deployment/snapshots/:snapshot/restore"): {
+		Summary:  "Replace the content of a volume with a snapshot, the replicas are stopped meanwhile",
+		Tag:      "volumes",
+		Request:  RestoreSnapshotRequest{},
+		Response: RestoreSnapshotResponse{},
+	},
+
+	routeKey(http.MethodGet, "/namespace/"): {
+		Summary:  "List the namespaces of the caller, or all namespaces for admins",
+		Tag:      "namespaces",
+		Query:    append([]queryParam{{Name: "name", Type: "string", Description: "Only namespaces with this in their name"}}, pageParams...),
+		Response: NamespaceListResponse{},
+	},
+	routeKey(http.MethodPost, "/namespace/"): {
+		Summary:  "Create a namespace, owned by the caller",
+		Tag:      "namespaces",
+		Request:  CreateNamespaceRequest{},
+		Response: MessageResponse{},
+	},
+	routeKey(http.MethodDelete, "/namespace/:namespace"): {
+		Summary: "Delete a namespace",
+		Tag:     "namespaces",
+		Query: []queryParam{
+			{Name: "cascade", Type: "boolean", Description: "Also delete the deployments of the namespace"},
+			volumesParam,
+		},
+		Response: DeleteNamespaceResponse{},
+	},
+	routeKey(http.MethodGet, "/namespace/:namespace/members"): {
+		Summary:  "List the members of a namespace",
+		Tag:      "members",
+		Response: []models.NamespaceMember{},
+	},
+	routeKey(http.MethodPut, "/namespace/:namespace/members"): {
+		Summary:  "Add a member to a namespace or change its role",
+		Tag:      "members",
+		Request:  SetMemberRequest{},
+		Response: models.NamespaceMember{},
+	},
+	routeKey(http.MethodDelete, "/namespace/:namespace/members/:user"): {
+		Summary:  "Remove a member from a namespace",
+		Tag:      "members",
+		Response: MessageResponse{},
+	},
+
+	routeKey(http.MethodGet, "/admin/users"): {
+		Summary:  "List users",
+		Tag:      "admin",
+		Response: []models.User{},
+	},
+	routeKey(http.MethodGet, "/admin/tokens"): {
+		Summary:  "List API tokens",
+		Tag:      "admin",
+		Query:    []queryParam{{Name: "user", Type: "string", Description: "Only tokens of this user"}},
+		Response: []models.ApiToken{},
+	},
+	routeKey(http.MethodPost, "/admin/tokens"): {
+		Summary:  "Create an API token, creating its user if needed",
+		Tag:      "admin",
+		Request:  CreateTokenRequest{},
+		Response: CreateTokenResponse{},
+		Status:   http.StatusCreated,
+	},
+	routeKey(http.MethodDelete, "/admin/tokens/:id"): {
+		Summary:  "Revoke an API token",
+		Tag:      "admin",
+		Response: MessageResponse{},
+	},
+
+	routeKey(http.MethodGet, "/auth/oidc"): {
+		Summary:  "Get the SSO configuration for the device authorization flow",
+		Tag:      "auth",
+		Response: OIDCConfigResponse{},
+		Public:   true,
+	},
+	routeKey(http.MethodPost, "/auth/oidc/token"): {
+		Summary:  "Exchange an ID token of the issuer for a session",
+		Tag:      "auth",
+		Request:  OIDCTokenRequest{},
+		Response: auth.Session{},
+		Public:   true,
+	},
+	routeKey(http.MethodPost, "/auth/refresh"): {
+		Summary:  "Exchange a refresh token for a new session",
+		Tag:      "auth",
+		Request:  RefreshRequest{},
+		Response: auth.Session{},
+		Public:   true,
+	},
+
+	routeKey(http.MethodGet, "/audit"): {
+		Summary: "List audit entries, newest first",
+		Tag:     "audit",
+		Query: append([]queryParam{
+			{Name: "actor", Type: "string", Description: "Only actions of this user"},
+			{Name: "namespace", Type: "string", Description: "Only actions in this namespace"},
+			{Name: "deployment", Type: "string", Description: "Only actions on this deployment"},
+			{Name: "action", Type: "string", Description: "Only this action, like deployment.upload"},
+			{Name: "since", Type: "string", Description: "Duration like 24h or RFC3339 time"},
+			{Name: "until", Type: "string", Description: "Duration like 24h or RFC3339 time"},
+		}, pageParams...),
+		Response: AuditListResponse{},
+	},
+}
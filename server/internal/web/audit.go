@@ -0,0 +1,145 @@
+package web
+
+import (
+	"bulut-server/internal/logic/audit"
+	"bulut-server/internal/logic/auth"
+	"bulut-server/pkg/orm/models"
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Longest request body kept in an audit entry
+const maxAuditPayload = 1024
+
+// Context keys handlers can set to complete the audit entry of a request
+const (
+	auditNamespaceKey  = "audit.namespace"
+	auditDeploymentKey = "audit.deployment"
+)
+
+// auditPayload summarizes the request for the audit log. Bodies are read up
+// to maxAuditPayload and put back for the handler, uploads are only counted.
+func auditPayload(c echo.Context) string {
+	req := c.Request()
+	var parts []string
+	if req.URL.RawQuery != "" {
+		parts = append(parts, "query: "+req.URL.RawQuery)
+	}
+
+	contentType := req.Header.Get(echo.HeaderContentType)
+	if strings.HasPrefix(contentType, echo.MIMEMultipartForm) {
+		parts = append(parts, fmt.Sprintf("upload: %d bytes", req.ContentLength))
+	} else if req.Body != nil && req.ContentLength != 0 {
+		body, err := io.ReadAll(io.LimitReader(req.Body, maxAuditPayload+1))
+		if err == nil {
+			req.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), req.Body))
+			summary := string(body)
+			if len(body) > maxAuditPayload {
+				summary = string(body[:maxAuditPayload]) + "..."
+			}
+			parts = append(parts, "body: "+summary)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func newAuditEntry(c echo.Context, action string) models.AuditEntry {
+	entry := models.AuditEntry{
+		Action:     action,
+		Namespace:  c.Param("namespace"),
+		Deployment: c.Param("deployment"),
+		SourceIP:   c.RealIP(),
+	}
+	if identity, ok := auth.FromContext(c.Request().Context()); ok {
+		entry.Actor = identity.UserName
+		if identity.UserID != uuid.Nil {
+			entry.UserID = &identity.UserID
+		}
+		if identity.TokenID != uuid.Nil {
+			entry.TokenID = &identity.TokenID
+		}
+	}
+	return entry
+}
+
+// audit records the request in the audit log once the handler is done, must
+// run after authMiddleware.
+func (s *Server) audit(action string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			entry := newAuditEntry(c, action)
+			entry.Payload = auditPayload(c)
+
+			handlerErr := next(c)
+
+			if ns, ok := c.Get(auditNamespaceKey).(string); ok {
+				entry.Namespace = ns
+			}
+			if dep, ok := c.Get(auditDeploymentKey).(string); ok {
+				entry.Deployment = dep
+			}
+			entry.Status = c.Response().Status
+			var apiErr *APIError
+			if errors.As(handlerErr, &apiErr) {
+				entry.Status = apiErr.Status
+			} else if he, ok := handlerErr.(*echo.HTTPError); ok {
+				entry.Status = he.Code
+			}
+			entry.Result = audit.ResultSuccess
+			if handlerErr != nil || entry.Status >= http.StatusBadRequest {
+				entry.Result = audit.ResultFailure
+			}
+			if handlerErr != nil {
+				entry.Error = handlerErr.Error()
+			}
+
+			if err := audit.Record(s.db, &entry); err != nil {
+				s.log(c).Error(err, "Failed to record audit entry", "action", action)
+			}
+			return handlerErr
+		}
+	}
+}
+
+func (s *Server) listAuditHandler(c echo.Context) error {
+	since, err := parseSince(c.QueryParam("since"))
+	if err != nil {
+		return newAPIError(http.StatusBadRequest, CodeBadRequest, "Invalid since query parameter")
+	}
+	until, err := parseSince(c.QueryParam("until"))
+	if err != nil {
+		return newAPIError(http.StatusBadRequest, CodeBadRequest, "Invalid until query parameter")
+	}
+	page, perPage := parsePage(c)
+
+	entries, total, err := audit.List(s.db, audit.Filter{
+		Actor:      c.QueryParam("actor"),
+		Namespace:  c.QueryParam("namespace"),
+		Deployment: c.QueryParam("deployment"),
+		Action:     c.QueryParam("action"),
+		Since:      since,
+		Until:      until,
+		Page:       page,
+		PerPage:    perPage,
+	})
+	if err != nil {
+		s.log(c).Error(err, "Failed to list audit entries")
+		return newAPIError(http.StatusInternalServerError, CodeInternal, "Failed to list audit entries")
+	}
+
+	return c.JSON(http.StatusOK, AuditListResponse{
+		Entries:    entries,
+		Pagination: newPagination(page, perPage, total),
+	})
+}
+
+type AuditListResponse struct {
+	Entries []models.AuditEntry `json:"entries"`
+	Pagination
+}
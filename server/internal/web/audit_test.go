@@ -0,0 +1,53 @@
+package web
+
+import (
+	"bulut-server/pkg/orm/models"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAuditSourceIP(t *testing.T) {
+	_, proxy, err := net.ParseCIDR("10.0.0.0/8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name           string
+		trustedProxies []*net.IPNet
+		remoteAddr     string
+		sourceIP       string
+	}{
+		{"forwarded header is ignored without trusted proxies", nil, "10.0.0.1:1234", "10.0.0.1"},
+		{"forwarded header of a client is ignored", []*net.IPNet{proxy}, "192.0.2.1:1234", "192.0.2.1"},
+		{"forwarded header of a trusted proxy is used", []*net.IPNet{proxy}, "10.0.0.1:1234", "203.0.113.7"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			s.IPExtractor = ipExtractor(tt.trustedProxies)
+
+			req := httptest.NewRequest(http.MethodPost, apiPrefix+"/namespace/", strings.NewReader(`{"name": "web"}`))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer test-key")
+			req.Header.Set("X-Forwarded-For", "203.0.113.7")
+			req.Header.Set("X-Real-IP", "203.0.113.7")
+			req.RemoteAddr = tt.remoteAddr
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, req)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body)
+			}
+
+			var entry models.AuditEntry
+			if err := s.db.Where("action = ?", "namespace.create").First(&entry).Error; err != nil {
+				t.Fatal(err)
+			}
+			if entry.SourceIP != tt.sourceIP {
+				t.Fatalf("expected source IP %s, got %s", tt.sourceIP, entry.SourceIP)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package web
+
+import (
+	"bulut-server/internal/logic/deploy"
+	"bulut-server/internal/logic/namespace"
+	"bulut-server/pkg/orm/common"
+	"bulut-server/pkg/orm/models"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"strconv"
+)
+
+// parseBoolQuery reads an optional boolean query parameter, false if missing.
+func parseBoolQuery(c echo.Context, name string) (bool, error) {
+	value := c.QueryParam(name)
+	if value == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(value)
+}
+
+func (s *Server) deleteDeploymentHandler(c echo.Context) error {
+	volumes, err := parseBoolQuery(c, "volumes")
+	if err != nil {
+		return newAPIError(http.StatusBadRequest, CodeBadRequest, "Invalid volumes query parameter")
+	}
+
+	deployment, err := s.findDeployment(c)
+	if deployment == nil {
+		return err
+	}
+
+	err = deploy.DeleteDeployment(deploy.ReplicaOpts{
+		Db:      s.db,
+		Logger:  s.log(c),
+		Gateway: s.gateway,
+		Context: c.Request().Context(),
+	}, *deployment, volumes)
+	if err != nil {
+		s.log(c).Error(err, "Failed to delete deployment", "deployment", deployment.ID)
+		return newAPIError(http.StatusInternalServerError, CodeInternal, "Failed to delete deployment")
+	}
+	s.deleteLogs(c, *deployment)
+
+	return c.JSON(http.StatusOK, MessageResponse{Message: "Deployment deleted successfully"})
+}
+
+func (s *Server) deleteNamespaceHandler(c echo.Context) error {
+	cascade, err := parseBoolQuery(c, "cascade")
+	if err != nil {
+		return newAPIError(http.StatusBadRequest, CodeBadRequest, "Invalid cascade query parameter")
+	}
+	volumes, err := parseBoolQuery(c, "volumes")
+	if err != nil {
+		return newAPIError(http.StatusBadRequest, CodeBadRequest, "Invalid volumes query parameter")
+	}
+
+	ns, err := namespace.FindNamespaceByName(s.db, c.Param("namespace"))
+	if err != nil {
+		if errors.Is(err, common.ErrNotFound) {
+			return newAPIError(http.StatusNotFound, CodeNotFound, "Namespace not found")
+		}
+		s.log(c).Error(err, "Failed to find namespace")
+		return newAPIError(http.StatusInternalServerError, CodeInternal, "Failed to find namespace")
+	}
+
+	deleted, err := deploy.DeleteNamespace(deploy.ReplicaOpts{
+		Db:      s.db,
+		Logger:  s.log(c),
+		Gateway: s.gateway,
+		Context: c.Request().Context(),
+	}, ns, cascade, volumes)
+	for _, deployment := range deleted {
+		s.deleteLogs(c, deployment)
+	}
+	if err != nil {
+		if errors.Is(err, deploy.ErrNamespaceNotEmpty) {
+			return newAPIError(http.StatusConflict, CodeNamespaceNotEmpty, "Namespace has deployments, delete them first or use cascade")
+		}
+		s.log(c).Error(err, "Failed to delete namespace", "namespace", ns.Name)
+		return newAPIError(http.StatusInternalServerError, CodeInternal, "Failed to delete namespace")
+	}
+
+	return c.JSON(http.StatusOK, DeleteNamespaceResponse{
+		Message:            "Namespace deleted successfully",
+		DeletedDeployments: len(deleted),
+	})
+}
+
+type DeleteNamespaceResponse struct {
+	Message            string `json:"message"`
+	DeletedDeployments int    `json:"deleted_deployments"`
+}
+
+func (s *Server) deleteLogs(c echo.Context, deployment models.Deployment) {
+	if s.logStore == nil {
+		return
+	}
+	if err := s.logStore.Delete(deployment.ID.String()); err != nil {
+		s.log(c).Error(err, "Failed to delete logs of deployment", "deployment", deployment.ID)
+	}
+}
@@ -0,0 +1,116 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"net/http"
+)
+
+// Codes of API errors. Clients rely on them, so they must not change once
+// released.
+const (
+	CodeBadRequest        = "bad_request"
+	CodeUnauthorized      = "unauthorized"
+	CodeTokenExpired      = "token_expired"
+	CodeTokenRevoked      = "token_revoked"
+	CodeForbidden         = "forbidden"
+	CodeNotFound          = "not_found"
+	CodeMethodNotAllowed  = "method_not_allowed"
+	CodeConflict          = "conflict"
+	CodeNamespaceNotEmpty = "namespace_not_empty"
+	CodeLastOwner         = "last_owner"
+	CodeTooLarge          = "too_large"
+	CodeNotConfigured     = "not_configured"
+	CodeUpstream          = "upstream_error"
+	CodeInternal          = "internal"
+)
+
+// APIError is the error returned by handlers. The HTTP error handler writes it
+// as {"error": {...}} with its status.
+type APIError struct {
+	Status  int                    `json:"-"`
+	Code    string                 `json:"code"`
+	Message string                 `json:"message"`
+	Details map[string]interface{} `json:"details,omitempty"`
+	// Set by the error handler from the X-Request-ID header
+	RequestID string `json:"request_id,omitempty"`
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+// WithDetail adds machine-readable context to the error, like the name of an
+// invalid field.
+func (e *APIError) WithDetail(key string, value interface{}) *APIError {
+	if e.Details == nil {
+		e.Details = make(map[string]interface{})
+	}
+	e.Details[key] = value
+	return e
+}
+
+// ErrorResponse is the body of every error response.
+type ErrorResponse struct {
+	Error *APIError `json:"error"`
+}
+
+// MessageResponse is the body of successful responses without a result.
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
+func newAPIError(status int, code, message string) *APIError {
+	return &APIError{Status: status, Code: code, Message: message}
+}
+
+// statusCodes are the codes of errors that do not come from handlers, like
+// unknown routes or bodies over the limit.
+var statusCodes = map[int]string{
+	http.StatusBadRequest:            CodeBadRequest,
+	http.StatusUnauthorized:          CodeUnauthorized,
+	http.StatusForbidden:             CodeForbidden,
+	http.StatusNotFound:              CodeNotFound,
+	http.StatusMethodNotAllowed:      CodeMethodNotAllowed,
+	http.StatusConflict:              CodeConflict,
+	http.StatusRequestEntityTooLarge: CodeTooLarge,
+}
+
+// errorHandler writes errors returned by handlers and middlewares as an
+// ErrorResponse. Errors that are not an APIError or an echo.HTTPError are
+// unexpected, they are logged and hidden from the client.
+func (s *Server) errorHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+
+	var apiErr *APIError
+	var httpErr *echo.HTTPError
+	switch {
+	case errors.As(err, &apiErr):
+	case errors.As(err, &httpErr):
+		code, ok := statusCodes[httpErr.Code]
+		if !ok {
+			code = CodeInternal
+		}
+		message := http.StatusText(httpErr.Code)
+		if m, ok := httpErr.Message.(string); ok {
+			message = m
+		}
+		apiErr = newAPIError(httpErr.Code, code, message)
+	default:
+		s.log(c).Error(err, "Unhandled error", "method", c.Request().Method, "path", c.Path())
+		apiErr = newAPIError(http.StatusInternalServerError, CodeInternal, "Internal server error")
+	}
+	apiErr.RequestID = requestID(c)
+
+	if c.Request().Method == http.MethodHead {
+		err = c.NoContent(apiErr.Status)
+	} else {
+		err = c.JSON(apiErr.Status, ErrorResponse{Error: apiErr})
+	}
+	if err != nil {
+		s.log(c).Error(err, "Failed to write error response")
+	}
+}
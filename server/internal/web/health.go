@@ -0,0 +1,79 @@
+package web
+
+import (
+	"bulut-server/internal/logic/auth"
+	"bulut-server/internal/logic/health"
+	"context"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"os"
+)
+
+type HealthResponse struct {
+	// ok if every check passes, failing otherwise
+	Status string          `json:"status"`
+	Checks []health.Result `json:"checks"`
+}
+
+// healthChecks are the dependencies the server needs to serve requests.
+func (s *Server) healthChecks() []health.Check {
+	checks := []health.Check{
+		{Name: "database", Check: health.Database(s.db)},
+		{Name: "docker", Check: health.Docker(s.dockerClient)},
+		{Name: "disk", Check: health.DiskSpace(os.TempDir(), uint64(s.config.MinFreeDiskMB)<<20)},
+	}
+	if s.gateway != nil {
+		checks = append(checks, health.Check{Name: "gateway", Check: func(context.Context) error {
+			return s.gateway.Status()
+		}})
+	}
+	return checks
+}
+
+// runHealthChecks runs the checks and logs changes of their outcome with the
+// errors. Only admins get the errors and timings in the response, everyone
+// else only learns which checks fail.
+func (s *Server) runHealthChecks(c echo.Context) (HealthResponse, bool) {
+	results, ok := health.Run(c.Request().Context(), s.healthChecks())
+	resp := HealthResponse{Status: health.StatusOK, Checks: results}
+	if !ok {
+		resp.Status = health.StatusFailing
+	}
+
+	if previous, _ := s.readiness.Swap(resp.Status).(string); previous != resp.Status {
+		if ok {
+			s.log(c).Info("Server is ready")
+		}
+		for _, result := range results {
+			if result.Status != health.StatusOK {
+				s.log(c).Warn("Server is not ready", "check", result.Name, "error", result.Error)
+			}
+		}
+	}
+
+	if identity, err := s.authenticate(c); err != nil || !identity.HasScope(auth.ScopeAdmin) {
+		resp.Checks = make([]health.Result, len(results))
+		for i, result := range results {
+			resp.Checks[i] = health.Result{Name: result.Name, Status: result.Status}
+		}
+	}
+	return resp, ok
+}
+
+// healthzHandler reports that the server is alive, with the checks of its
+// dependencies for diagnostics. It fails only when the server cannot answer,
+// so the server is not restarted for an outage of a dependency.
+func (s *Server) healthzHandler(c echo.Context) error {
+	resp, _ := s.runHealthChecks(c)
+	return c.JSON(http.StatusOK, resp)
+}
+
+// readyzHandler reports whether the server can serve requests, with 503 until
+// every dependency is reachable.
+func (s *Server) readyzHandler(c echo.Context) error {
+	resp, ok := s.runHealthChecks(c)
+	if !ok {
+		return c.JSON(http.StatusServiceUnavailable, resp)
+	}
+	return c.JSON(http.StatusOK, resp)
+}
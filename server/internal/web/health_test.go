@@ -0,0 +1,59 @@
+package web
+
+import (
+	"bulut-server/internal/logic/auth"
+	"bulut-server/internal/logic/health"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHealthDetailsAreForAdmins(t *testing.T) {
+	s := newTestServer(t)
+	_, readToken, err := auth.CreateToken(s.db, "reader", "test", []auth.Scope{auth.ScopeRead}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		auth    string
+		details bool
+	}{
+		{"unauthenticated", "", false},
+		{"invalid token", "Bearer wrong", false},
+		{"read token", "Bearer " + readToken, false},
+		{"admin", "Bearer test-key", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, req)
+
+			// Docker is not connected in tests
+			if rec.Code != http.StatusServiceUnavailable {
+				t.Fatalf("expected status %d, got %d: %s", http.StatusServiceUnavailable, rec.Code, rec.Body)
+			}
+			var resp HealthResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			var docker *health.Result
+			for i := range resp.Checks {
+				if resp.Checks[i].Name == "docker" {
+					docker = &resp.Checks[i]
+				}
+			}
+			if docker == nil || docker.Status == health.StatusOK {
+				t.Fatalf("expected a failing docker check, got %s", rec.Body)
+			}
+			if details := docker.Error != ""; details != tt.details {
+				t.Fatalf("expected details %t, got %s", tt.details, rec.Body)
+			}
+		})
+	}
+}
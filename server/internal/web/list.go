@@ -0,0 +1,173 @@
+package web
+
+import (
+	"bulut-server/internal/logic/auth"
+	"bulut-server/internal/logic/deploy"
+	"bulut-server/internal/logic/namespace"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type NamespaceSummary struct {
+	Name        string    `json:"name"`
+	Deployments int64     `json:"deployments"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type ReplicaSummary struct {
+	ContainerID string `json:"container_id"`
+	Address     string `json:"address"`
+	State       string `json:"state"`
+}
+
+type DeploymentSummary struct {
+	Name          string           `json:"name"`
+	Namespace     string           `json:"namespace"`
+	Status        string           `json:"status"`
+	Replicas      int              `json:"replicas"`
+	Running       int              `json:"running"`
+	Balancer      string           `json:"balancer"`
+	RestartPolicy string           `json:"restart_policy"`
+	RestartCount  int              `json:"restart_count"`
+	CreatedAt     time.Time        `json:"created_at"`
+	UpdatedAt     time.Time        `json:"updated_at"`
+	Containers    []ReplicaSummary `json:"containers"`
+}
+
+type NamespaceListResponse struct {
+	Namespaces []NamespaceSummary `json:"namespaces"`
+	Pagination
+}
+
+type DeploymentListResponse struct {
+	Deployments []DeploymentSummary `json:"deployments"`
+	Pagination
+}
+
+// Pagination is embedded in the responses of list endpoints.
+type Pagination struct {
+	Page    int   `json:"page"`
+	PerPage int   `json:"per_page"`
+	Total   int64 `json:"total"`
+	Pages   int   `json:"pages"`
+}
+
+func newPagination(page, perPage int, total int64) Pagination {
+	return Pagination{Page: page, PerPage: perPage, Total: total, Pages: pageCount(total, perPage)}
+}
+
+// parsePage reads the page and per_page query parameters, falling back to
+// the first page of 50.
+func parsePage(c echo.Context) (int, int) {
+	page, err := strconv.Atoi(c.QueryParam("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	perPage, err := strconv.Atoi(c.QueryParam("per_page"))
+	if err != nil || perPage < 1 || perPage > 500 {
+		perPage = 50
+	}
+	return page, perPage
+}
+
+func pageCount(total int64, perPage int) int {
+	return int(math.Ceil(float64(total) / float64(perPage)))
+}
+
+// listNamespacesHandler lists all namespaces for admins and the namespaces
+// the caller is a member of for everyone else.
+func (s *Server) listNamespacesHandler(c echo.Context) error {
+	page, perPage := parsePage(c)
+	filter := namespace.ListFilter{
+		Name:    c.QueryParam("name"),
+		Page:    page,
+		PerPage: perPage,
+	}
+	identity, _ := auth.FromContext(c.Request().Context())
+	if !identity.HasScope(auth.ScopeAdmin) {
+		filter.MemberID = identity.UserID
+	}
+
+	namespaces, total, err := namespace.ListNamespaces(s.db, filter)
+	if err != nil {
+		s.log(c).Error(err, "Failed to list namespaces")
+		return newAPIError(http.StatusInternalServerError, CodeInternal, "Failed to list namespaces")
+	}
+
+	summaries := make([]NamespaceSummary, 0, len(namespaces))
+	for _, ns := range namespaces {
+		summaries = append(summaries, NamespaceSummary{
+			Name:        ns.Name,
+			Deployments: ns.DeploymentCount,
+			CreatedAt:   ns.CreatedAt,
+		})
+	}
+	return c.JSON(http.StatusOK, NamespaceListResponse{
+		Namespaces: summaries,
+		Pagination: newPagination(page, perPage, total),
+	})
+}
+
+func (s *Server) listDeploymentsHandler(c echo.Context) error {
+	status := c.QueryParam("status")
+	if status != "" && !deploy.ValidStatus(status) {
+		s.log(c).Warn("Invalid status filter", "status", status)
+		return newAPIError(http.StatusBadRequest, CodeBadRequest, fmt.Sprintf("Status must be one of %s, %s, %s, %s, %s or %s",
+			deploy.StatusRunning, deploy.StatusDegraded, deploy.StatusStopped,
+			deploy.StatusCrashLooping, deploy.StatusNotDeployed, deploy.StatusUnknown))
+	}
+
+	ns, err := namespace.FindNamespaceByName(s.db, c.Param("namespace"))
+	if err != nil {
+		s.log(c).Error(err, "Failed to find namespace")
+		return newAPIError(http.StatusInternalServerError, CodeInternal, "Failed to find namespace")
+	}
+
+	page, perPage := parsePage(c)
+	deployments, total, err := deploy.ListDeployments(s.db, s.dockerClient, deploy.ListFilter{
+		NamespaceID: ns.ID,
+		Name:        c.QueryParam("name"),
+		Status:      status,
+		Page:        page,
+		PerPage:     perPage,
+	})
+	if err != nil {
+		s.log(c).Error(err, "Failed to list deployments")
+		return newAPIError(http.StatusInternalServerError, CodeInternal, "Failed to list deployments")
+	}
+
+	summaries := make([]DeploymentSummary, 0, len(deployments))
+	for _, dep := range deployments {
+		summary := DeploymentSummary{
+			Name:          dep.Name,
+			Namespace:     ns.Name,
+			Status:        dep.Status,
+			Replicas:      dep.Replicas,
+			Balancer:      dep.Balancer,
+			RestartPolicy: dep.RestartPolicy,
+			RestartCount:  dep.RestartCount,
+			CreatedAt:     dep.CreatedAt,
+			UpdatedAt:     dep.UpdatedAt,
+			Containers:    make([]ReplicaSummary, 0, len(dep.ReplicaStates)),
+		}
+		for _, replica := range dep.ReplicaStates {
+			if replica.State == "running" {
+				summary.Running++
+			}
+			summary.Containers = append(summary.Containers, ReplicaSummary{
+				ContainerID: replica.ContainerID,
+				Address:     replica.Address,
+				State:       replica.State,
+			})
+		}
+		summaries = append(summaries, summary)
+	}
+	return c.JSON(http.StatusOK, DeploymentListResponse{
+		Deployments: summaries,
+		Pagination:  newPagination(page, perPage, total),
+	})
+}
@@ -0,0 +1,164 @@
+package web
+
+import (
+	"bulut-server/internal/logic/deploy"
+	"bulut-server/internal/logic/logs"
+	"bulut-server/internal/logic/revision"
+	"bulut-server/pkg/orm/common"
+	"encoding/json"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// parseSince accepts a duration relative to now (10m), an RFC3339 time or a
+// unix timestamp.
+func parseSince(since string) (time.Time, error) {
+	if since == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(since); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, since); err == nil {
+		return t, nil
+	}
+	unix, err := strconv.ParseInt(since, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(unix, 0), nil
+}
+
+// logsHandler streams the output of all replicas as newline delimited JSON.
+func (s *Server) logsHandler(c echo.Context) error {
+	tail := c.QueryParam("tail")
+	if tail == "" {
+		tail = "all"
+	} else if n, err := strconv.Atoi(tail); tail != "all" && (err != nil || n < 0) {
+		s.log(c).Warn("Invalid tail query parameter", "tail", tail)
+		return newAPIError(http.StatusBadRequest, CodeBadRequest, "Invalid tail query parameter")
+	}
+	since, err := parseSince(c.QueryParam("since"))
+	if err != nil {
+		s.log(c).Warn("Invalid since query parameter", "since", c.QueryParam("since"))
+		return newAPIError(http.StatusBadRequest, CodeBadRequest, "Invalid since query parameter")
+	}
+	timestamps, _ := strconv.ParseBool(c.QueryParam("timestamps"))
+	follow, _ := strconv.ParseBool(c.QueryParam("follow"))
+
+	deployment, err := s.findDeployment(c)
+	if deployment == nil {
+		return err
+	}
+
+	containers, err := deploy.FindContainers(s.db, *deployment)
+	if err != nil {
+		s.log(c).Error(err, "Failed to find containers")
+		return newAPIError(http.StatusInternalServerError, CodeInternal, "Failed to find containers")
+	}
+	if len(containers) == 0 {
+		return newAPIError(http.StatusNotFound, CodeNotFound, "Deployment has no running replicas")
+	}
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "application/x-ndjson")
+	res.WriteHeader(http.StatusOK)
+	res.Flush()
+
+	enc := json.NewEncoder(res)
+	err = logs.Stream(c.Request().Context(), s.dockerClient, containers, logs.StreamOptions{
+		Tail:       tail,
+		Since:      since,
+		Timestamps: timestamps,
+		Follow:     follow,
+	}, func(line logs.Line) error {
+		if err := enc.Encode(line); err != nil {
+			return err
+		}
+		res.Flush()
+		return nil
+	})
+	if err != nil && c.Request().Context().Err() == nil {
+		// Headers are already sent, the error can only be logged
+		s.log(c).Error(err, "Failed to stream logs")
+	}
+	return nil
+}
+
+// logHistoryHandler searches the logs collected from current and previous
+// replicas of the deployment.
+func (s *Server) logHistoryHandler(c echo.Context) error {
+	if s.logStore == nil {
+		return newAPIError(http.StatusServiceUnavailable, CodeNotConfigured, "Log storage is not available")
+	}
+
+	since, err := parseSince(c.QueryParam("since"))
+	if err != nil {
+		s.log(c).Warn("Invalid since query parameter", "since", c.QueryParam("since"))
+		return newAPIError(http.StatusBadRequest, CodeBadRequest, "Invalid since query parameter")
+	}
+	until, err := parseSince(c.QueryParam("until"))
+	if err != nil {
+		s.log(c).Warn("Invalid until query parameter", "until", c.QueryParam("until"))
+		return newAPIError(http.StatusBadRequest, CodeBadRequest, "Invalid until query parameter")
+	}
+	limit := 1000
+	if limitStr := c.QueryParam("limit"); limitStr != "" {
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit < 1 || limit > 10000 {
+			s.log(c).Warn("Invalid limit query parameter", "limit", limitStr)
+			return newAPIError(http.StatusBadRequest, CodeBadRequest, "Limit must be between 1 and 10000")
+		}
+	}
+	query := logs.Query{
+		Since:    since,
+		Until:    until,
+		Contains: c.QueryParam("contains"),
+		Limit:    limit,
+	}
+	if pattern := c.QueryParam("regex"); pattern != "" {
+		query.Regex, err = regexp.Compile(pattern)
+		if err != nil {
+			s.log(c).Warn("Invalid regex query parameter", "regex", pattern)
+			return newAPIError(http.StatusBadRequest, CodeBadRequest, "Invalid regex: "+err.Error())
+		}
+	}
+
+	deployment, err := s.findDeployment(c)
+	if deployment == nil {
+		return err
+	}
+
+	if rev := c.QueryParam("revision"); rev != "" {
+		found, err := revision.FindRevision(s.db, deployment.ID, rev)
+		if err != nil {
+			if errors.Is(err, common.ErrNotFound) {
+				return newAPIError(http.StatusNotFound, CodeNotFound, "Revision not found")
+			}
+			s.log(c).Error(err, "Failed to find revision")
+			return newAPIError(http.StatusInternalServerError, CodeInternal, "Failed to find revision")
+		}
+		query.RevisionID = found.ID.String()
+	}
+
+	lines, err := s.logStore.Search(deployment.ID.String(), query)
+	if err != nil {
+		s.log(c).Error(err, "Failed to search logs")
+		return newAPIError(http.StatusInternalServerError, CodeInternal, "Failed to search logs")
+	}
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "application/x-ndjson")
+	res.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(res)
+	for _, line := range lines {
+		if err := enc.Encode(line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package web
+
+import (
+	"bulut-server/internal/logic/auth"
+	"bulut-server/internal/logic/namespace"
+	"bulut-server/pkg/orm/common"
+	"bulut-server/pkg/orm/models"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"net/http"
+)
+
+// authorizeNamespace checks that the caller has at least the role in the
+// namespace. If it returns false, the returned error should be returned from
+// the handler.
+func (s *Server) authorizeNamespace(c echo.Context, ns models.Namespace, role auth.Role) (bool, error) {
+	identity, ok := auth.FromContext(c.Request().Context())
+	if !ok {
+		return false, newAPIError(http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+	}
+	var member auth.Role
+	if !identity.HasScope(auth.ScopeAdmin) {
+		var err error
+		member, err = namespace.FindMemberRole(s.db, ns.ID, identity.UserID)
+		if err != nil {
+			s.log(c).Error(err, "Failed to find namespace role")
+			return false, newAPIError(http.StatusInternalServerError, CodeInternal, "Failed to find namespace role")
+		}
+	}
+	if !identity.CanAccessNamespace(member, role) {
+		return false, newAPIError(http.StatusForbidden, CodeForbidden, "Forbidden: "+string(role)+" role required in namespace "+ns.Name)
+	}
+	return true, nil
+}
+
+// requireRole rejects callers without the role in the namespace of the
+// :namespace path parameter, must run after authMiddleware.
+func (s *Server) requireRole(role auth.Role) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			ns, err := namespace.FindNamespaceByName(s.db, c.Param("namespace"))
+			if err != nil {
+				if errors.Is(err, common.ErrNotFound) {
+					return newAPIError(http.StatusNotFound, CodeNotFound, "Namespace not found")
+				}
+				s.log(c).Error(err, "Failed to find namespace")
+				return newAPIError(http.StatusInternalServerError, CodeInternal, "Failed to find namespace")
+			}
+			if ok, err := s.authorizeNamespace(c, ns, role); !ok {
+				return err
+			}
+			return next(c)
+		}
+	}
+}
+
+func (s *Server) listMembersHandler(c echo.Context) error {
+	ns, err := namespace.FindNamespaceByName(s.db, c.Param("namespace"))
+	if err != nil {
+		s.log(c).Error(err, "Failed to find namespace")
+		return newAPIError(http.StatusInternalServerError, CodeInternal, "Failed to find namespace")
+	}
+
+	members, err := namespace.ListMembers(s.db, ns.ID)
+	if err != nil {
+		s.log(c).Error(err, "Failed to list members")
+		return newAPIError(http.StatusInternalServerError, CodeInternal, "Failed to list members")
+	}
+	return c.JSON(http.StatusOK, members)
+}
+
+type SetMemberRequest struct {
+	User string `json:"user" form:"user" validate:"required"`
+	Role string `json:"role" form:"role" validate:"required,oneof=viewer deployer owner"`
+}
+
+func (s *Server) setMemberHandler(c echo.Context) error {
+	var req SetMemberRequest
+	err := c.Bind(&req)
+	if err != nil {
+		return newAPIError(http.StatusBadRequest, CodeBadRequest, "Bad request")
+	}
+	role := auth.Role(req.Role)
+
+	ns, err := namespace.FindNamespaceByName(s.db, c.Param("namespace"))
+	if err != nil {
+		s.log(c).Error(err, "Failed to find namespace")
+		return newAPIError(http.StatusInternalServerError, CodeInternal, "Failed to find namespace")
+	}
+	// Only admins may add users that have never logged in or had a token
+	identity, _ := auth.FromContext(c.Request().Context())
+	var user models.User
+	if identity.HasScope(auth.ScopeAdmin) {
+		user, err = auth.FindOrCreateUser(s.db, req.User)
+	} else {
+		user, err = auth.FindUser(s.db, req.User)
+	}
+	if err != nil {
+		if errors.Is(err, common.ErrNotFound) {
+			return newAPIError(http.StatusNotFound, CodeNotFound, "User not found")
+		}
+		s.log(c).Error(err, "Failed to find user")
+		return newAPIError(http.StatusInternalServerError, CodeInternal, "Failed to find user")
+	}
+
+	member, err := namespace.SetMember(s.db, ns.ID, user.ID, role)
+	if err != nil {
+		if err == namespace.ErrLastOwner {
+			return newAPIError(http.StatusConflict, CodeLastOwner, "Namespace must keep at least one owner")
+		}
+		s.log(c).Error(err, "Failed to set member")
+		return newAPIError(http.StatusInternalServerError, CodeInternal, "Failed to set member")
+	}
+	member.User = user
+
+	return c.JSON(http.StatusOK, member)
+}
+
+func (s *Server) removeMemberHandler(c echo.Context) error {
+	ns, err := namespace.FindNamespaceByName(s.db, c.Param("namespace"))
+	if err != nil {
+		s.log(c).Error(err, "Failed to find namespace")
+		return newAPIError(http.StatusInternalServerError, CodeInternal, "Failed to find namespace")
+	}
+
+	var user models.User
+	err = s.db.Where("name = ?", c.Param("user")).First(&user).Error
+	if err == nil {
+		err = namespace.RemoveMember(s.db, ns.ID, user.ID)
+	}
+	if err != nil {
+		if errors.Is(err, common.ErrNotFound) {
+			return newAPIError(http.StatusNotFound, CodeNotFound, "Member not found")
+		}
+		if err == namespace.ErrLastOwner {
+			return newAPIError(http.StatusConflict, CodeLastOwner, "Namespace must keep at least one owner")
+		}
+		s.log(c).Error(err, "Failed to remove member")
+		return newAPIError(http.StatusInternalServerError, CodeInternal, "Failed to remove member")
+	}
+
+	return c.JSON(http.StatusOK, MessageResponse{Message: "Member removed successfully"})
+}
@@ -0,0 +1,37 @@
+package web
+
+import (
+	"bulut-server/internal/metrics"
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"strconv"
+	"time"
+)
+
+var metricsHTTPHandler = promhttp.Handler()
+
+// metricsMiddleware counts and times requests by route. Errors are written
+// here, so their status is counted.
+func (s *Server) metricsMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		start := time.Now()
+		err := next(c)
+		if err != nil {
+			c.Error(err)
+		}
+
+		route := c.Path()
+		if route == "" {
+			route = "unmatched"
+		}
+		method := c.Request().Method
+		metrics.HTTPRequests.WithLabelValues(method, route, strconv.Itoa(c.Response().Status)).Inc()
+		metrics.HTTPRequestDuration.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+		return nil
+	}
+}
+
+func (s *Server) metricsHandler(c echo.Context) error {
+	metricsHTTPHandler.ServeHTTP(c.Response(), c.Request())
+	return nil
+}
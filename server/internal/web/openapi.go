@@ -0,0 +1,457 @@
+package web
+
+import (
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OpenAPI is an OpenAPI 3.0 document of the API, generated from the routes
+// and the documentation in apiDocs.
+type OpenAPI struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       OpenAPIInfo         `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components OpenAPIComponents   `json:"components"`
+}
+
+type OpenAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type OpenAPIComponents struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes"`
+}
+
+type SecurityScheme struct {
+	Type string `json:"type"`
+	In   string `json:"in"`
+	Name string `json:"name"`
+}
+
+// PathItem maps lower case methods to operations.
+type PathItem map[string]*Operation
+
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary"`
+	Tags        []string              `json:"tags"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+	Security    []map[string][]string `json:"security"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Required    bool    `json:"required"`
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Minimum              *int               `json:"minimum,omitempty"`
+	Maximum              *int               `json:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+// Version of the API described by the document, its major version is the one
+// served under apiPrefix
+const apiVersion = "1.0.0"
+
+const (
+	mimeJSON      = "application/json"
+	mimeNDJSON    = "application/x-ndjson"
+	mimeMultipart = "multipart/form-data"
+)
+
+// routeDoc documents a route. The schemas of the bodies are derived from the
+// types of Request and Response, which are zero values of the types the
+// handler binds and returns.
+type routeDoc struct {
+	Summary string
+	Tag     string
+	Query   []queryParam
+	Request interface{}
+	// Content type of the request body, JSON if empty
+	RequestType string
+	Response    interface{}
+	// Content type of the response, JSON if empty
+	ResponseType string
+	// Status of a successful response, 200 if zero
+	Status int
+	// Routes that do not need an API key
+	Public bool
+}
+
+type queryParam struct {
+	Name string
+	// string, integer or boolean
+	Type        string
+	Description string
+	Enum        []string
+}
+
+var pathParamPattern = regexp.MustCompile(`:([^/]+)`)
+
+// openAPIPath turns an echo path like /deployment/:namespace into
+// /deployment/{namespace}.
+func openAPIPath(path string) string {
+	return pathParamPattern.ReplaceAllString(path, "{$1}")
+}
+
+func routeKey(method, path string) string {
+	return method + " " + path
+}
+
+// docKey returns the key of a route in apiDocs, whose paths are without the
+// API prefix.
+func docKey(route *echo.Route) string {
+	return routeKey(route.Method, strings.TrimPrefix(route.Path, apiPrefix))
+}
+
+// Name of the handler echo registers for groups with middleware, the routes
+// with it only exist to run the middleware of unknown paths
+var notFoundHandlerName = runtime.FuncForPC(reflect.ValueOf(echo.NotFoundHandler).Pointer()).Name()
+
+// apiRoutes returns the routes registered by ConfigureRoutes, sorted by path
+// and method. The legacy paths of routes served under the API prefix are left
+// out.
+func (s *Server) apiRoutes() []*echo.Route {
+	registered := make(map[string]bool)
+	for _, route := range s.Routes() {
+		registered[routeKey(route.Method, route.Path)] = true
+	}
+
+	var routes []*echo.Route
+	for _, route := range s.Routes() {
+		if route.Name == notFoundHandlerName || registered[routeKey(route.Method, apiPrefix+route.Path)] {
+			continue
+		}
+		routes = append(routes, route)
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+	return routes
+}
+
+// UndocumentedRoutes lists the routes without an entry in apiDocs, like
+// "GET /deployment/:namespace". They are left out of the OpenAPI document and
+// their requests are not validated.
+func (s *Server) UndocumentedRoutes() []string {
+	var missing []string
+	for _, route := range s.apiRoutes() {
+		if _, ok := apiDocs[docKey(route)]; !ok {
+			missing = append(missing, routeKey(route.Method, route.Path))
+		}
+	}
+	return missing
+}
+
+// OpenAPI returns the OpenAPI document of the server, built when the server
+// is created.
+func (s *Server) OpenAPI() *OpenAPI {
+	return s.openAPI
+}
+
+func (s *Server) buildOpenAPI() *OpenAPI {
+	gen := &schemaGenerator{
+		schemas: make(map[string]*Schema),
+		names:   make(map[reflect.Type]string),
+	}
+	errorSchema := gen.schemaOf(reflect.TypeOf(ErrorResponse{}))
+
+	doc := &OpenAPI{
+		OpenAPI: "3.0.3",
+		Info:    OpenAPIInfo{Title: "Bulut API", Version: apiVersion},
+		Paths:   make(map[string]PathItem),
+		Components: OpenAPIComponents{
+			Schemas: gen.schemas,
+			SecuritySchemes: map[string]SecurityScheme{
+				"apiKey": {Type: "apiKey", In: "header", Name: "Authorization"},
+			},
+		},
+	}
+	for _, route := range s.apiRoutes() {
+		routeDoc, ok := apiDocs[docKey(route)]
+		if !ok {
+			continue
+		}
+		path := openAPIPath(route.Path)
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = make(PathItem)
+		}
+		doc.Paths[path][strings.ToLower(route.Method)] = gen.operation(route, routeDoc, errorSchema)
+	}
+	return doc
+}
+
+func (gen *schemaGenerator) operation(route *echo.Route, doc routeDoc, errorSchema *Schema) *Operation {
+	op := &Operation{
+		OperationID: operationID(route),
+		Summary:     doc.Summary,
+		Tags:        []string{doc.Tag},
+		Responses:   make(map[string]Response),
+		Security:    []map[string][]string{},
+	}
+	if !doc.Public {
+		op.Security = append(op.Security, map[string][]string{"apiKey": {}})
+	}
+
+	for _, match := range pathParamPattern.FindAllStringSubmatch(route.Path, -1) {
+		op.Parameters = append(op.Parameters, Parameter{
+			Name:     match[1],
+			In:       "path",
+			Required: true,
+			Schema:   &Schema{Type: "string"},
+		})
+	}
+	for _, param := range doc.Query {
+		op.Parameters = append(op.Parameters, Parameter{
+			Name:        param.Name,
+			In:          "query",
+			Description: param.Description,
+			Schema:      &Schema{Type: param.Type, Enum: param.Enum},
+		})
+	}
+
+	if doc.Request != nil {
+		contentType := doc.RequestType
+		if contentType == "" {
+			contentType = mimeJSON
+		}
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]MediaType{contentType: {Schema: gen.schemaOf(reflect.TypeOf(doc.Request))}},
+		}
+	}
+
+	status := doc.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	response := Response{Description: http.StatusText(status)}
+	if doc.Response != nil {
+		contentType := doc.ResponseType
+		if contentType == "" {
+			contentType = mimeJSON
+		}
+		response.Content = map[string]MediaType{contentType: {Schema: gen.schemaOf(reflect.TypeOf(doc.Response))}}
+	}
+	op.Responses[strconv.Itoa(status)] = response
+	op.Responses["default"] = Response{
+		Description: "Error",
+		Content:     map[string]MediaType{mimeJSON: {Schema: errorSchema}},
+	}
+	return op
+}
+
+// operationID derives an ID like deleteDeployment from the handler name
+// deleteDeploymentHandler.
+func operationID(route *echo.Route) string {
+	name := route.Name[strings.LastIndex(route.Name, ".")+1:]
+	name = strings.TrimSuffix(name, "-fm")
+	return strings.TrimSuffix(name, "Handler")
+}
+
+// schemaGenerator derives schemas from Go types the way encoding/json encodes
+// them. Named structs become components referenced by name.
+type schemaGenerator struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	deletedAtType = reflect.TypeOf(gorm.DeletedAt{})
+	uuidType      = reflect.TypeOf(uuid.UUID{})
+	fileType      = reflect.TypeOf(multipart.FileHeader{})
+)
+
+func (gen *schemaGenerator) schemaOf(t reflect.Type) *Schema {
+	if t.Kind() == reflect.Pointer {
+		schema := gen.schemaOf(t.Elem())
+		if schema.Ref != "" {
+			// Siblings of $ref are ignored in OpenAPI 3.0
+			return schema
+		}
+		copied := *schema
+		copied.Nullable = true
+		return &copied
+	}
+
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case deletedAtType:
+		return &Schema{Type: "string", Format: "date-time", Nullable: true}
+	case uuidType:
+		return &Schema{Type: "string", Format: "uuid"}
+	case fileType:
+		return &Schema{Type: "string", Format: "binary"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: gen.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: gen.schemaOf(t.Elem())}
+	case reflect.Struct:
+		return gen.structSchema(t)
+	}
+	// interface{} can be anything
+	return &Schema{}
+}
+
+func (gen *schemaGenerator) structSchema(t reflect.Type) *Schema {
+	if t.Name() == "" {
+		schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+		gen.addFields(schema, t)
+		return schema
+	}
+
+	name, ok := gen.names[t]
+	if !ok {
+		name = t.Name()
+		if _, taken := gen.schemas[name]; taken {
+			// Same name in another package
+			pkg := t.PkgPath()[strings.LastIndex(t.PkgPath(), "/")+1:]
+			name = strings.ToUpper(pkg[:1]) + pkg[1:] + name
+		}
+		gen.names[t] = name
+		// Registered before the fields for types that refer to themselves
+		schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+		gen.schemas[name] = schema
+		gen.addFields(schema, t)
+	}
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// addFields adds the fields of a struct to its schema, with the fields of
+// embedded structs inlined as encoding/json does.
+func (gen *schemaGenerator) addFields(schema *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			gen.addFields(schema, field.Type)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		fieldSchema := gen.schemaOf(field.Type)
+		if rules := field.Tag.Get("validate"); rules != "" {
+			required := applyRules(fieldSchema, rules)
+			if required {
+				schema.Required = append(schema.Required, name)
+			}
+		}
+		schema.Properties[name] = fieldSchema
+	}
+}
+
+// applyRules adds the constraints of a validate tag to a field schema and
+// reports whether the field is required. Rules are separated by commas:
+//
+//	required     must be present, strings must not be empty
+//	min=n,max=n  bounds of integers, or of the length of strings
+//	oneof=a b c  allowed values of strings
+func applyRules(schema *Schema, rules string) bool {
+	required := false
+	for _, rule := range strings.Split(rules, ",") {
+		key, value, _ := strings.Cut(rule, "=")
+		switch key {
+		case "required":
+			required = true
+			if schema.Type == "string" && schema.MinLength == nil {
+				one := 1
+				schema.MinLength = &one
+			}
+		case "min", "max":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				panic("invalid validate rule: " + rule)
+			}
+			switch {
+			case schema.Type == "string" && key == "min":
+				schema.MinLength = &n
+			case schema.Type == "string":
+				schema.MaxLength = &n
+			case key == "min":
+				schema.Minimum = &n
+			default:
+				schema.Maximum = &n
+			}
+		case "oneof":
+			schema.Enum = strings.Fields(value)
+		default:
+			panic("unknown validate rule: " + rule)
+		}
+	}
+	return required
+}
+
+func (s *Server) openAPIHandler(c echo.Context) error {
+	return c.JSON(http.StatusOK, s.openAPI)
+}
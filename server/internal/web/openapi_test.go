@@ -0,0 +1,90 @@
+package web
+
+import (
+	"bulut-server/internal/logic/auth"
+	"bulut-server/pkg/logger"
+	"bulut-server/pkg/orm/common"
+	"bulut-server/pkg/orm/migrations"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTestServer returns a server with an empty SQLite database, the audit
+// log of its requests needs one.
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	db, err := common.ConnectDB(&common.DatabaseConfig{
+		Driver: common.DriverSQLite,
+		DBPath: filepath.Join(t.TempDir(), "bulut.db"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrations.Up(db); err != nil {
+		t.Fatal(err)
+	}
+	return NewServer(&ServerConfig{ApiKey: "test-key"}, ServerUtils{
+		Logger: logger.New(logger.Options{Level: logger.ErrorLevel}),
+		Db:     db,
+	})
+}
+
+func TestRoutesAreDocumented(t *testing.T) {
+	if missing := newTestServer(t).UndocumentedRoutes(); len(missing) > 0 {
+		t.Fatalf("routes missing from apiDocs: %s", strings.Join(missing, ", "))
+	}
+}
+
+func TestValidateRequest(t *testing.T) {
+	s := newTestServer(t)
+	_, readToken, err := auth.CreateToken(s.db, "reader", "test", []auth.Scope{auth.ScopeRead}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name        string
+		auth        string
+		contentType string
+		body        string
+		status      int
+		field       string
+	}{
+		{"unauthenticated callers are rejected before validation", "", "application/json", `{"name": 1}`, http.StatusUnauthorized, ""},
+		{"callers without the scope are rejected before validation", "Bearer " + readToken, "application/json", `{"name": 1}`, http.StatusForbidden, ""},
+		{"JSON body", "Bearer test-key", "application/json", `{"name": "app", "namespace": "web"}`, http.StatusBadRequest, "name"},
+		{"form body", "Bearer test-key", "application/x-www-form-urlencoded", "name=app-1", http.StatusBadRequest, "namespace"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, apiPrefix+"/deployment/", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("expected status %d, got %d: %s", tt.status, rec.Code, rec.Body)
+			}
+			if tt.field == "" {
+				return
+			}
+			var resp struct {
+				Error struct {
+					Details map[string]interface{} `json:"details"`
+				} `json:"error"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Error.Details["field"] != tt.field {
+				t.Fatalf("expected an error for field %s, got %s", tt.field, rec.Body)
+			}
+		})
+	}
+}
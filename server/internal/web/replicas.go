@@ -0,0 +1,115 @@
+package web
+
+import (
+	"bulut-server/internal/gateway"
+	"bulut-server/internal/logic/deploy"
+	"errors"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"net/http"
+)
+
+type ScaleDeploymentRequest struct {
+	Replicas int    `json:"replicas" form:"replicas" validate:"required,min=1,max=10"`
+	Balancer string `json:"balancer" form:"balancer" validate:"oneof=round-robin least-connections"`
+}
+
+func (s *Server) scaleHandler(c echo.Context) error {
+	var req ScaleDeploymentRequest
+	err := c.Bind(&req)
+	if err != nil {
+		return newAPIError(http.StatusBadRequest, CodeBadRequest, "Bad request")
+	}
+	if req.Replicas < 1 || req.Replicas > deploy.MaxReplicas {
+		s.log(c).Warn("Invalid replica count", "replicas", req.Replicas)
+		return newAPIError(http.StatusBadRequest, CodeBadRequest, fmt.Sprintf("Replicas must be between 1 and %d", deploy.MaxReplicas))
+	}
+	balancer := gateway.Strategy(req.Balancer)
+	if balancer != "" && !balancer.Valid() {
+		s.log(c).Warn("Invalid balancer", "balancer", req.Balancer)
+		return newAPIError(http.StatusBadRequest, CodeBadRequest, fmt.Sprintf("Balancer must be %s or %s", gateway.RoundRobin, gateway.LeastConnections))
+	}
+
+	deployment, err := s.findDeployment(c)
+	if deployment == nil {
+		return err
+	}
+
+	scaled, err := deploy.Scale(deploy.ReplicaOpts{
+		Db:      s.db,
+		Logger:  s.log(c),
+		Gateway: s.gateway,
+		Context: c.Request().Context(),
+	}, *deployment, req.Replicas, balancer)
+	if errors.Is(err, deploy.ErrDeploymentDeleted) {
+		return newAPIError(http.StatusNotFound, CodeNotFound, "Deployment not found")
+	}
+	if err != nil {
+		s.log(c).Error(err, "Failed to scale deployment")
+		return newAPIError(http.StatusInternalServerError, CodeInternal, "Failed to scale deployment")
+	}
+
+	return c.JSON(http.StatusOK, ScaleDeploymentResponse{
+		Message:  "Deployment scaled successfully",
+		Replicas: scaled.Replicas,
+		Balancer: scaled.Balancer,
+	})
+}
+
+type ScaleDeploymentResponse struct {
+	Message  string `json:"message"`
+	Replicas int    `json:"replicas"`
+	Balancer string `json:"balancer"`
+}
+
+type RestartPolicyRequest struct {
+	Policy     string `json:"policy" form:"policy" validate:"required,oneof=no on-failure always"`
+	MaxRetries int    `json:"max_retries" form:"max_retries" validate:"min=0"`
+}
+
+func (s *Server) restartPolicyHandler(c echo.Context) error {
+	var req RestartPolicyRequest
+	err := c.Bind(&req)
+	if err != nil {
+		return newAPIError(http.StatusBadRequest, CodeBadRequest, "Bad request")
+	}
+	if !deploy.ValidRestartPolicy(req.Policy) {
+		s.log(c).Warn("Invalid restart policy", "policy", req.Policy)
+		return newAPIError(http.StatusBadRequest, CodeBadRequest, fmt.Sprintf("Policy must be %s, %s or %s", deploy.RestartNever, deploy.RestartOnFailure, deploy.RestartAlways))
+	}
+	if req.MaxRetries < 0 {
+		s.log(c).Warn("Invalid max retries", "max_retries", req.MaxRetries)
+		return newAPIError(http.StatusBadRequest, CodeBadRequest, "Max retries cannot be negative")
+	}
+
+	deployment, err := s.findDeployment(c)
+	if deployment == nil {
+		return err
+	}
+
+	updated, err := deploy.SetRestartPolicy(deploy.ReplicaOpts{
+		Db:      s.db,
+		Logger:  s.log(c),
+		Gateway: s.gateway,
+		Context: c.Request().Context(),
+	}, *deployment, req.Policy, req.MaxRetries)
+	if errors.Is(err, deploy.ErrDeploymentDeleted) {
+		return newAPIError(http.StatusNotFound, CodeNotFound, "Deployment not found")
+	}
+	if err != nil {
+		s.log(c).Error(err, "Failed to set restart policy")
+		return newAPIError(http.StatusInternalServerError, CodeInternal, "Failed to set restart policy")
+	}
+
+	return c.JSON(http.StatusOK, RestartPolicyResponse{
+		Message:    "Restart policy updated successfully",
+		Policy:     updated.RestartPolicy,
+		MaxRetries: updated.RestartMaxRetries,
+	})
+}
+
+type RestartPolicyResponse struct {
+	Message    string `json:"message"`
+	Policy     string `json:"policy"`
+	MaxRetries int    `json:"max_retries"`
+}
@@ -1,239 +1,344 @@
 package web
 
 import (
+	"bulut-server/internal/logic/audit"
+	"bulut-server/internal/logic/auth"
 	"bulut-server/internal/logic/deploy"
 	"bulut-server/internal/logic/namespace"
+	"bulut-server/internal/metrics"
+	"bulut-server/internal/tracing"
+	"bulut-server/pkg/orm/common"
+	"bulut-server/pkg/orm/models"
+	"crypto/subtle"
+	"errors"
 	"github.com/labstack/echo/v4"
-	"gorm.io/gorm"
 	"io"
 	"net/http"
 	"os"
+	"strings"
 )
 
 func (s *Server) ConfigureRoutes() {
 	s.logger.Info("Configuring routes...")
 
-	deploymentGrp := s.Group("/deployment", s.authMiddleware)
-	deploymentGrp.GET("/:namespace/:deployment", s.getDeploymentHandler)
-	deploymentGrp.POST("/", s.createDeploymentHandler)
-	deploymentGrp.PUT("/upload/:namespace/:deployment", s.uploadHandler)
+	s.GET("/openapi.json", s.openAPIHandler)
+	s.GET("/version", s.versionHandler)
+	s.GET("/healthz", s.healthzHandler)
+	s.GET("/readyz", s.readyzHandler)
+	s.GET("/metrics", s.metricsHandler, s.authMiddleware, s.requireScope(auth.ScopeAdmin))
 
-	namespaceGrp := s.Group("/namespace", s.authMiddleware)
-	namespaceGrp.POST("/", s.createNamespaceHandler)
+	s.configureAPI(apiPrefix)
+	// The paths from before versioning, until clients use the prefix
+	s.configureAPI("", s.legacyRoute)
 }
 
+// validatedGroup validates requests after the middleware of the group and of
+// the route, so callers that may not use a route learn nothing from it.
+type validatedGroup struct {
+	*echo.Group
+	validate echo.MiddlewareFunc
+}
+
+func (g validatedGroup) add(method, path string, h echo.HandlerFunc, m []echo.MiddlewareFunc) *echo.Route {
+	return g.Add(method, path, h, append(append([]echo.MiddlewareFunc{}, m...), g.validate)...)
+}
+
+func (g validatedGroup) GET(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route {
+	return g.add(http.MethodGet, path, h, m)
+}
+
+func (g validatedGroup) POST(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route {
+	return g.add(http.MethodPost, path, h, m)
+}
+
+func (g validatedGroup) PUT(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route {
+	return g.add(http.MethodPut, path, h, m)
+}
+
+func (g validatedGroup) DELETE(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route {
+	return g.add(http.MethodDelete, path, h, m)
+}
+
+// configureAPI registers the routes of the API under the prefix, with the
+// middleware running before the middleware of each group. Requests are
+// validated once the caller passed the auth and role checks.
+func (s *Server) configureAPI(prefix string, middleware ...echo.MiddlewareFunc) {
+	group := func(path string, groupMiddleware ...echo.MiddlewareFunc) validatedGroup {
+		g := s.Group(prefix+path, append(append([]echo.MiddlewareFunc{}, middleware...), groupMiddleware...)...)
+		return validatedGroup{Group: g, validate: s.validateRequest}
+	}
+
+	viewer := s.requireRole(auth.RoleViewer)
+	deployer := s.requireRole(auth.RoleDeployer)
+	owner := s.requireRole(auth.RoleOwner)
+
+	deploymentGrp := group("/deployment", s.authMiddleware)
+	deploymentGrp.GET("/:namespace", s.listDeploymentsHandler, viewer)
+	deploymentGrp.GET("/:namespace/:deployment", s.getDeploymentHandler, viewer)
+	deploymentGrp.GET("/:namespace/:deployment/logs", s.logsHandler, viewer)
+	deploymentGrp.GET("/:namespace/:deployment/logs/history", s.logHistoryHandler, viewer)
+	// The namespace is in the body, the role is checked by the handler
+	deploymentGrp.POST("/", s.createDeploymentHandler, s.audit("deployment.create"), s.requireScope(auth.ScopeDeploy))
+	deploymentGrp.PUT("/upload/:namespace/:deployment", s.uploadHandler, s.audit("deployment.upload"), deployer)
+	deploymentGrp.PUT("/scale/:namespace/:deployment", s.scaleHandler, s.audit("deployment.scale"), deployer)
+	deploymentGrp.PUT("/restart-policy/:namespace/:deployment", s.restartPolicyHandler, s.audit("deployment.restart-policy"), deployer)
+	deploymentGrp.DELETE("/:namespace/:deployment", s.deleteDeploymentHandler, s.audit("deployment.delete"), deployer)
+	deploymentGrp.GET("/:namespace/:deployment/volumes", s.listVolumesHandler, viewer)
+	deploymentGrp.PUT("/:namespace/:deployment/volumes", s.setVolumesHandler, s.audit("deployment.volumes"), deployer)
+	deploymentGrp.GET("/:namespace/:deployment/snapshots", s.listSnapshotsHandler, viewer)
+	deploymentGrp.POST("/:namespace/:deployment/snapshots", s.createSnapshotHandler, s.audit("deployment.snapshot"), deployer)
+	deploymentGrp.POST("/:namespace/:deployment/snapshots/:snapshot/restore", s.restoreSnapshotHandler, s.audit("deployment.restore"), deployer)
+
+	namespaceGrp := group("/namespace", s.authMiddleware)
+	namespaceGrp.GET("/", s.listNamespacesHandler)
+	namespaceGrp.POST("/", s.createNamespaceHandler, s.audit("namespace.create"), s.requireScope(auth.ScopeDeploy))
+	namespaceGrp.DELETE("/:namespace", s.deleteNamespaceHandler, s.audit("namespace.delete"), owner)
+	namespaceGrp.GET("/:namespace/members", s.listMembersHandler, viewer)
+	namespaceGrp.PUT("/:namespace/members", s.setMemberHandler, s.audit("namespace.member.set"), owner)
+	namespaceGrp.DELETE("/:namespace/members/:user", s.removeMemberHandler, s.audit("namespace.member.remove"), owner)
+
+	adminGrp := group("/admin", s.authMiddleware, s.requireScope(auth.ScopeAdmin))
+	adminGrp.GET("/users", s.listUsersHandler)
+	adminGrp.GET("/tokens", s.listTokensHandler)
+	adminGrp.POST("/tokens", s.createTokenHandler, s.audit("token.create"))
+	adminGrp.DELETE("/tokens/:id", s.revokeTokenHandler, s.audit("token.revoke"))
+
+	authGrp := group("/auth")
+	authGrp.GET("/oidc", s.oidcConfigHandler)
+	authGrp.POST("/oidc/token", s.oidcTokenHandler)
+	authGrp.POST("/refresh", s.refreshHandler)
+
+	auditGrp := group("/audit", s.authMiddleware, s.requireScope(auth.ScopeAdmin))
+	auditGrp.GET("", s.listAuditHandler)
+}
+
+// authMiddleware resolves the caller from the authorization header, either
+// the bootstrap API_KEY or an API token, and attaches it to the request context.
 func (s *Server) authMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		reqApiKey := c.Request().Header.Get("authorization")
-		if s.config.ApiKey != reqApiKey {
-			return c.JSON(http.StatusUnauthorized, map[string]string{
-				"error": "Unauthorized",
-			})
+		identity, err := s.authenticate(c)
+		if err != nil {
+			return err
 		}
+		c.SetRequest(c.Request().WithContext(auth.WithIdentity(c.Request().Context(), identity)))
 		return next(c)
 	}
 }
 
-func (s *Server) getDeploymentHandler(c echo.Context) error {
-	namespaceName := c.Param("namespace")
-	deploymentName := c.Param("deployment")
+// authenticate resolves the caller from the authorization header. The error
+// is the API error to answer unauthenticated callers with.
+func (s *Server) authenticate(c echo.Context) (auth.Identity, error) {
+	// Tools like Prometheus can only send it as a bearer token
+	reqApiKey := strings.TrimPrefix(c.Request().Header.Get("authorization"), "Bearer ")
+	if reqApiKey == "" {
+		return auth.Identity{}, newAPIError(http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+	}
+	if subtle.ConstantTimeCompare([]byte(s.config.ApiKey), []byte(reqApiKey)) == 1 {
+		return auth.Bootstrap, nil
+	}
 
-	dep, err := deploy.FindDeploymentByName(s.db, namespaceName, deploymentName)
+	identity, err := auth.Authenticate(s.db, reqApiKey)
 	if err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return c.JSON(http.StatusNotFound, map[string]string{
-				"error": "Deployment not found",
-			})
+		if err == auth.ErrTokenExpired {
+			return identity, newAPIError(http.StatusUnauthorized, CodeTokenExpired, "Unauthorized: "+err.Error())
 		}
-		s.logger.Error(err, "Failed to get deployment")
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to get deployment",
-		})
+		if err == auth.ErrTokenRevoked {
+			return identity, newAPIError(http.StatusUnauthorized, CodeTokenRevoked, "Unauthorized: "+err.Error())
+		}
+		if err != auth.ErrInvalidToken {
+			s.log(c).Error(err, "Failed to authenticate token")
+		}
+		return identity, newAPIError(http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+	}
+	return identity, nil
+}
+
+// requireScope rejects callers without the scope, must run after authMiddleware.
+func (s *Server) requireScope(scope auth.Scope) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			identity, ok := auth.FromContext(c.Request().Context())
+			if !ok || !identity.HasScope(scope) {
+				return newAPIError(http.StatusForbidden, CodeForbidden, "Forbidden: "+string(scope)+" scope required")
+			}
+			return next(c)
+		}
+	}
+}
+
+// findDeployment looks up the deployment from the :namespace and :deployment
+// path parameters. If it returns nil, the returned error should be returned
+// from the handler.
+func (s *Server) findDeployment(c echo.Context) (*models.Deployment, error) {
+	namespace, err := namespace.FindNamespaceByName(s.db, c.Param("namespace"))
+	if err != nil {
+		if errors.Is(err, common.ErrNotFound) {
+			return nil, newAPIError(http.StatusNotFound, CodeNotFound, "Namespace not found")
+		}
+		s.log(c).Error(err, "Failed to find namespace")
+		return nil, newAPIError(http.StatusInternalServerError, CodeInternal, "Failed to find namespace")
+	}
+
+	deployment, err := deploy.FindDeploymentByName(s.db, c.Param("deployment"), namespace.ID.String())
+	if err != nil {
+		if errors.Is(err, common.ErrNotFound) {
+			return nil, newAPIError(http.StatusNotFound, CodeNotFound, "Deployment not found")
+		}
+		s.log(c).Error(err, "Failed to find deployment")
+		return nil, newAPIError(http.StatusInternalServerError, CodeInternal, "Failed to find deployment")
+	}
+	deployment.Namespace = namespace
+
+	return &deployment, nil
+}
+
+func (s *Server) getDeploymentHandler(c echo.Context) error {
+	dep, err := s.findDeployment(c)
+	if dep == nil {
+		return err
 	}
 
 	return c.JSON(http.StatusOK, dep)
 }
 
 type CreateNamespaceRequest struct {
-	Name string `json:"name" form:"name"`
+	Name string `json:"name" form:"name" validate:"required"`
 }
 
 func (s *Server) createNamespaceHandler(c echo.Context) error {
 	var req CreateNamespaceRequest
 	err := c.Bind(&req)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Bad request",
-		})
-	}
-	if req.Name == "" {
-		s.logger.Warn("Missing name in body")
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Missing name in body",
-		})
+		return newAPIError(http.StatusBadRequest, CodeBadRequest, "Bad request")
 	}
+	c.Set(auditNamespaceKey, req.Name)
 
-	_, err = namespace.CreateNamespace(s.db, req.Name)
+	identity, _ := auth.FromContext(c.Request().Context())
+	_, err = namespace.CreateNamespace(s.db, req.Name, identity.UserID)
 	if err != nil {
-		if err.Error() == "UNIQUE constraint failed: namespaces.name" {
-			return c.JSON(http.StatusConflict, map[string]string{
-				"error": "Namespace with this name already exists",
-			})
+		if errors.Is(err, common.ErrDuplicate) {
+			return newAPIError(http.StatusConflict, CodeConflict, "Namespace with this name already exists")
 		}
-		s.logger.Error(err, "Failed to create namespace")
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to create namespace",
-		})
+		s.log(c).Error(err, "Failed to create namespace")
+		return newAPIError(http.StatusInternalServerError, CodeInternal, "Failed to create namespace")
 	}
 
-	return c.JSON(http.StatusOK, map[string]string{
-		"message": "Namespace created successfully",
-	})
+	return c.JSON(http.StatusOK, MessageResponse{Message: "Namespace created successfully"})
 }
 
 type CreateDeploymentRequest struct {
-	Name      string `json:"name" form:"name"`
-	Namespace string `json:"namespace" form:"namespace"`
+	Name      string `json:"name" form:"name" validate:"required,min=4,max=50"`
+	Namespace string `json:"namespace" form:"namespace" validate:"required"`
 }
 
 func (s *Server) createDeploymentHandler(c echo.Context) error {
 	var req CreateDeploymentRequest
 	err := c.Bind(&req)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Bad request",
-		})
-	}
-	if req.Name == "" {
-		s.logger.Warn("Missing name in body")
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Missing name in body",
-		})
-	} else if req.Namespace == "" {
-		s.logger.Warn("Missing namespace in body")
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Missing namespace in body",
-		})
-	} else if len(req.Name) < 4 {
-		s.logger.Warn("Name is too short")
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Name is too short",
-		})
-	} else if len(req.Name) > 50 {
-		s.logger.Warn("Name is too long")
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Name is too long",
-		})
+		return newAPIError(http.StatusBadRequest, CodeBadRequest, "Bad request")
 	}
+	c.Set(auditNamespaceKey, req.Namespace)
+	c.Set(auditDeploymentKey, req.Name)
 
 	namespace, err := namespace.FindNamespaceByName(s.db, req.Namespace)
 	if err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return c.JSON(http.StatusNotFound, map[string]string{
-				"error": "Namespace not found",
-			})
+		if errors.Is(err, common.ErrNotFound) {
+			return newAPIError(http.StatusNotFound, CodeNotFound, "Namespace not found")
 		}
-		s.logger.Error(err, "Failed to find namespace")
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to find namespace",
-		})
+		s.log(c).Error(err, "Failed to find namespace")
+		return newAPIError(http.StatusInternalServerError, CodeInternal, "Failed to find namespace")
+	}
+
+	if ok, err := s.authorizeNamespace(c, namespace, auth.RoleDeployer); !ok {
+		return err
 	}
 
 	_, err = deploy.CreateDeployment(s.db, req.Name, namespace.ID)
 	if err != nil {
-		if err.Error() == "UNIQUE constraint failed: deployments.name, deployments.namespace_id" {
-			return c.JSON(http.StatusConflict, map[string]string{
-				"error": "Deployment with this name already exists",
-			})
+		if errors.Is(err, common.ErrDuplicate) {
+			return newAPIError(http.StatusConflict, CodeConflict, "Deployment with this name already exists")
 		}
-		s.logger.Error(err, "Failed to create deployment")
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to create deployment",
-		})
+		s.log(c).Error(err, "Failed to create deployment")
+		return newAPIError(http.StatusInternalServerError, CodeInternal, "Failed to create deployment")
 	}
 
-	return c.JSON(http.StatusCreated, map[string]string{
-		"message": "Deployment created successfully",
-	})
+	return c.JSON(http.StatusCreated, MessageResponse{Message: "Deployment created successfully"})
 }
 
 func (s *Server) uploadHandler(c echo.Context) error {
 	entrypoint := c.QueryParam("entrypoint")
 	if entrypoint == "" {
-		s.logger.Warn("Missing entrypoint query parameter")
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Missing entrypoint query parameter",
-		})
+		s.log(c).Warn("Missing entrypoint query parameter")
+		return newAPIError(http.StatusBadRequest, CodeBadRequest, "Missing entrypoint query parameter")
 	}
 
 	namespace, err := namespace.FindNamespaceByName(s.db, c.Param("namespace"))
 	if err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return c.JSON(http.StatusNotFound, map[string]string{
-				"error": "Namespace not found",
-			})
+		if errors.Is(err, common.ErrNotFound) {
+			return newAPIError(http.StatusNotFound, CodeNotFound, "Namespace not found")
 		}
-		s.logger.Error(err, "Failed to find namespace")
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to find namespace",
-		})
+		s.log(c).Error(err, "Failed to find namespace")
+		return newAPIError(http.StatusInternalServerError, CodeInternal, "Failed to find namespace")
 	}
 	namespaceId := namespace.ID.String()
 
 	deployment, err := deploy.FindDeploymentByName(s.db, c.Param("deployment"), namespaceId)
 	if err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return c.JSON(http.StatusNotFound, map[string]string{
-				"error": "Deployment not found",
-			})
+		if errors.Is(err, common.ErrNotFound) {
+			return newAPIError(http.StatusNotFound, CodeNotFound, "Deployment not found")
 		}
-		s.logger.Error(err, "Failed to find deployment")
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to find deployment",
-		})
+		s.log(c).Error(err, "Failed to find deployment")
+		return newAPIError(http.StatusInternalServerError, CodeInternal, "Failed to find deployment")
 	}
 	deploymentId := deployment.ID
 
-	wholeForm, err := c.MultipartForm()
-	if err != nil {
-		s.logger.Warn("Failed to parse form-data", "error", err)
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Failed to parse form-data",
-		})
-	}
-	s.logger.Info("Received deployment request", "form", wholeForm, "entrypoint", entrypoint)
 	file, err := c.FormFile("file")
 	if err != nil {
-		s.logger.Warn("Failed to retrieve file from form-data", "error", err)
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Failed to retrieve file from form-data",
-		})
+		s.log(c).Warn("Failed to retrieve file from form-data", "error", err)
+		return newAPIError(http.StatusBadRequest, CodeBadRequest, "Failed to retrieve file from form-data")
 	}
 
 	src, err := file.Open()
 	if err != nil {
-		s.logger.Warn("Failed to open file", "error", err)
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Failed to open file",
-		})
+		s.log(c).Warn("Failed to open file", "error", err)
+		return newAPIError(http.StatusBadRequest, CodeBadRequest, "Failed to open file")
 	}
 	defer src.Close()
 
 	tempFilename := deploy.GenerateTempFilename()
 	dst, err := os.Create(tempFilename)
 	if err != nil {
-		s.logger.Warn("Failed to create temporary file", "error", err)
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to create temporary file",
-		})
+		s.log(c).Warn("Failed to create temporary file", "error", err)
+		return newAPIError(http.StatusInternalServerError, CodeInternal, "Failed to create temporary file")
 	}
 	defer dst.Close()
 
-	if _, err = io.Copy(dst, src); err != nil {
-		s.logger.Error(err, "Failed to save uploaded file")
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to save uploaded file",
-		})
+	size, err := io.Copy(dst, src)
+	if err != nil {
+		s.log(c).Error(err, "Failed to save uploaded file")
+		return newAPIError(http.StatusInternalServerError, CodeInternal, "Failed to save uploaded file")
+	}
+	metrics.UploadSize.Observe(float64(size))
+
+	// The build outlives the request and must not use c once the handler
+	// returned. Its spans continue the trace of the upload, its logs have the
+	// ID of the upload request and its outcome is recorded as its own entry.
+	buildCtx := tracing.Detach(c.Request().Context())
+	buildLogger := s.log(c)
+	buildEntry := newAuditEntry(c, "deployment.build")
+	buildEntry.Payload = "entrypoint: " + entrypoint
+	onFinish := func(rev *models.Revision, err error) {
+		buildEntry.Result = audit.ResultSuccess
+		if err != nil {
+			buildEntry.Result = audit.ResultFailure
+			buildEntry.Error = err.Error()
+		}
+		if rev != nil {
+			buildEntry.RevisionID = &rev.ID
+		}
+		if err := audit.Record(s.db, &buildEntry); err != nil {
+			buildLogger.Error(err, "Failed to record audit entry", "action", buildEntry.Action)
+		}
 	}
 
 	go func() {
@@ -243,14 +348,21 @@ func (s *Server) uploadHandler(c echo.Context) error {
 			FilePath:     tempFilename,
 			Entrypoint:   entrypoint,
 			Db:           s.db,
-			Logger:       s.logger,
+			Logger:       buildLogger,
+			Gateway:      s.gateway,
+			Context:      buildCtx,
+			OnFinish:     onFinish,
 		})
 	}()
 
-	response := map[string]string{
-		"message": "Build in Progress",
-		"file":    tempFilename,
-	}
+	return c.JSON(http.StatusOK, UploadResponse{
+		Message: "Build in Progress",
+		File:    tempFilename,
+	})
+}
 
-	return c.JSON(http.StatusOK, response)
+type UploadResponse struct {
+	Message string `json:"message"`
+	// Path of the uploaded archive on the server
+	File string `json:"file"`
 }
@@ -0,0 +1,107 @@
+package web
+
+import (
+	"bulut-server/internal/logic/audit"
+	"bulut-server/internal/logic/auth"
+	"bulut-server/internal/logic/oidc"
+	"bulut-server/pkg/orm/models"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"net/http"
+)
+
+// oidcConfigHandler tells the CLI where to run the device authorization flow.
+func (s *Server) oidcConfigHandler(c echo.Context) error {
+	if s.oidc == nil {
+		return newAPIError(http.StatusNotFound, CodeNotConfigured, "SSO login is not configured on this server")
+	}
+	config := s.oidc.Config()
+	return c.JSON(http.StatusOK, OIDCConfigResponse{
+		Issuer:   config.Issuer,
+		ClientID: config.ClientID,
+		Scopes:   config.Scopes,
+	})
+}
+
+type OIDCConfigResponse struct {
+	Issuer   string   `json:"issuer"`
+	ClientID string   `json:"client_id"`
+	Scopes   []string `json:"scopes"`
+}
+
+type OIDCTokenRequest struct {
+	IDToken string `json:"id_token" form:"id_token" validate:"required"`
+}
+
+// oidcTokenHandler exchanges an ID token from the issuer for a bulut session.
+func (s *Server) oidcTokenHandler(c echo.Context) error {
+	if s.oidc == nil {
+		return newAPIError(http.StatusNotFound, CodeNotConfigured, "SSO login is not configured on this server")
+	}
+	var req OIDCTokenRequest
+	if err := c.Bind(&req); err != nil || req.IDToken == "" {
+		return newAPIError(http.StatusBadRequest, CodeBadRequest, "Missing id_token in body")
+	}
+
+	claims, err := s.oidc.Verify(c.Request().Context(), req.IDToken)
+	if err != nil {
+		if errors.Is(err, oidc.ErrInvalidIDToken) {
+			s.log(c).Warn("Rejected ID token", "error", err)
+			return newAPIError(http.StatusUnauthorized, CodeUnauthorized, "Invalid ID token")
+		}
+		s.log(c).Error(err, "Failed to verify ID token")
+		return newAPIError(http.StatusBadGateway, CodeUpstream, "Failed to verify ID token with the issuer")
+	}
+
+	config := s.oidc.Config()
+	user, scopes, err := oidc.Login(s.db, config, claims)
+	if err != nil {
+		s.log(c).Error(err, "Failed to log in SSO user")
+		return newAPIError(http.StatusInternalServerError, CodeInternal, "Failed to log in")
+	}
+	session, err := auth.CreateSession(s.db, user, scopes, claims.Groups, config.SessionTTL, config.RefreshTTL)
+	if err != nil {
+		s.log(c).Error(err, "Failed to create session")
+		return newAPIError(http.StatusInternalServerError, CodeInternal, "Failed to create session")
+	}
+
+	s.recordLogin(c, user, "auth.login")
+	return c.JSON(http.StatusOK, session)
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" form:"refresh_token" validate:"required"`
+}
+
+func (s *Server) refreshHandler(c echo.Context) error {
+	if s.oidc == nil {
+		return newAPIError(http.StatusNotFound, CodeNotConfigured, "SSO login is not configured on this server")
+	}
+	var req RefreshRequest
+	if err := c.Bind(&req); err != nil || req.RefreshToken == "" {
+		return newAPIError(http.StatusBadRequest, CodeBadRequest, "Missing refresh_token in body")
+	}
+
+	session, err := oidc.Refresh(s.db, s.oidc.Config(), req.RefreshToken)
+	if err != nil {
+		if err == auth.ErrInvalidToken || err == auth.ErrTokenExpired || err == auth.ErrTokenRevoked {
+			return newAPIError(http.StatusUnauthorized, CodeUnauthorized, "Unauthorized: "+err.Error()+", log in again")
+		}
+		s.log(c).Error(err, "Failed to refresh session")
+		return newAPIError(http.StatusInternalServerError, CodeInternal, "Failed to refresh session")
+	}
+
+	s.recordLogin(c, session.User, "auth.refresh")
+	return c.JSON(http.StatusOK, session)
+}
+
+func (s *Server) recordLogin(c echo.Context, user models.User, action string) {
+	entry := newAuditEntry(c, action)
+	entry.Actor = user.Name
+	entry.UserID = &user.ID
+	entry.Status = http.StatusOK
+	entry.Result = audit.ResultSuccess
+	if err := audit.Record(s.db, &entry); err != nil {
+		s.log(c).Error(err, "Failed to record audit entry", "action", action)
+	}
+}
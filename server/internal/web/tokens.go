@@ -0,0 +1,107 @@
+package web
+
+import (
+	"bulut-server/internal/logic/auth"
+	"bulut-server/pkg/orm/common"
+	"bulut-server/pkg/orm/models"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"time"
+)
+
+func (s *Server) listUsersHandler(c echo.Context) error {
+	users, err := auth.ListUsers(s.db)
+	if err != nil {
+		s.log(c).Error(err, "Failed to list users")
+		return newAPIError(http.StatusInternalServerError, CodeInternal, "Failed to list users")
+	}
+	return c.JSON(http.StatusOK, users)
+}
+
+func (s *Server) listTokensHandler(c echo.Context) error {
+	tokens, err := auth.ListTokens(s.db, c.QueryParam("user"))
+	if err != nil {
+		s.log(c).Error(err, "Failed to list tokens")
+		return newAPIError(http.StatusInternalServerError, CodeInternal, "Failed to list tokens")
+	}
+	return c.JSON(http.StatusOK, tokens)
+}
+
+type CreateTokenRequest struct {
+	User string `json:"user" form:"user" validate:"required"`
+	Name string `json:"name" form:"name"`
+	// Comma separated: read, deploy or admin
+	Scopes string `json:"scopes" form:"scopes" validate:"required"`
+	// Go duration like 720h, tokens without it do not expire
+	ExpiresIn string `json:"expires_in" form:"expires_in"`
+}
+
+func (s *Server) createTokenHandler(c echo.Context) error {
+	var req CreateTokenRequest
+	err := c.Bind(&req)
+	if err != nil {
+		return newAPIError(http.StatusBadRequest, CodeBadRequest, "Bad request")
+	}
+
+	scopes := auth.ParseScopes(req.Scopes)
+	if len(scopes) == 0 {
+		s.log(c).Warn("No scopes in body", "scopes", req.Scopes)
+		return newAPIError(http.StatusBadRequest, CodeBadRequest, "Scopes must list read, deploy or admin")
+	}
+	for _, scope := range scopes {
+		if !scope.Valid() {
+			s.log(c).Warn("Invalid scope", "scope", scope)
+			return newAPIError(http.StatusBadRequest, CodeBadRequest, "Invalid scope: "+string(scope))
+		}
+	}
+
+	var expiresAt *time.Time
+	if req.ExpiresIn != "" {
+		expiresIn, err := time.ParseDuration(req.ExpiresIn)
+		if err != nil || expiresIn <= 0 {
+			s.log(c).Warn("Invalid expires_in", "expires_in", req.ExpiresIn)
+			return newAPIError(http.StatusBadRequest, CodeBadRequest, "Invalid expires_in")
+		}
+		t := time.Now().Add(expiresIn)
+		expiresAt = &t
+	}
+
+	token, plain, err := auth.CreateToken(s.db, req.User, req.Name, scopes, expiresAt)
+	if err != nil {
+		s.log(c).Error(err, "Failed to create token")
+		return newAPIError(http.StatusInternalServerError, CodeInternal, "Failed to create token")
+	}
+
+	return c.JSON(http.StatusCreated, CreateTokenResponse{
+		Message: "Token created successfully, it will not be shown again",
+		Token:   plain,
+		Details: token,
+	})
+}
+
+type CreateTokenResponse struct {
+	Message string `json:"message"`
+	// The token itself, only returned here
+	Token   string          `json:"token"`
+	Details models.ApiToken `json:"details"`
+}
+
+func (s *Server) revokeTokenHandler(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return newAPIError(http.StatusBadRequest, CodeBadRequest, "Invalid token ID")
+	}
+
+	_, err = auth.RevokeToken(s.db, id)
+	if err != nil {
+		if errors.Is(err, common.ErrNotFound) {
+			return newAPIError(http.StatusNotFound, CodeNotFound, "Token not found")
+		}
+		s.log(c).Error(err, "Failed to revoke token")
+		return newAPIError(http.StatusInternalServerError, CodeInternal, "Failed to revoke token")
+	}
+
+	return c.JSON(http.StatusOK, MessageResponse{Message: "Token revoked successfully"})
+}
@@ -0,0 +1,249 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// validateRequest rejects requests whose query parameters, JSON or form body
+// do not match the OpenAPI document, before they reach the handler. Handlers
+// still check what the document cannot express. Requests of undocumented
+// routes and other bodies, like uploads, pass as they are.
+func (s *Server) validateRequest(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		op := s.operation(c.Request().Method, c.Path())
+		if op == nil {
+			return next(c)
+		}
+		if err := validateQuery(op, c.QueryParams()); err != nil {
+			return err
+		}
+		if op.RequestBody == nil {
+			return next(c)
+		}
+		media, ok := op.RequestBody.Content[mimeJSON]
+		if !ok {
+			return next(c)
+		}
+
+		var value interface{} = map[string]interface{}{}
+		contentType := c.Request().Header.Get(echo.HeaderContentType)
+		switch {
+		case strings.HasPrefix(contentType, echo.MIMEApplicationJSON):
+			body, err := io.ReadAll(c.Request().Body)
+			if err != nil {
+				return newAPIError(http.StatusBadRequest, CodeBadRequest, "Failed to read body")
+			}
+			c.Request().Body = io.NopCloser(bytes.NewReader(body))
+			if len(bytes.TrimSpace(body)) > 0 {
+				dec := json.NewDecoder(bytes.NewReader(body))
+				dec.UseNumber()
+				if err := dec.Decode(&value); err != nil {
+					return newAPIError(http.StatusBadRequest, CodeBadRequest, "Invalid JSON body")
+				}
+			}
+		case strings.HasPrefix(contentType, echo.MIMEApplicationForm):
+			// The parsed form stays on the request for the handler to bind
+			form, err := c.FormParams()
+			if err != nil {
+				return newAPIError(http.StatusBadRequest, CodeBadRequest, "Invalid form body")
+			}
+			value = s.formValue(media.Schema, form)
+		default:
+			return next(c)
+		}
+		if err := s.validateValue(media.Schema, value, ""); err != nil {
+			return err
+		}
+		return next(c)
+	}
+}
+
+// formValue turns a form into the value its JSON body would decode to, with
+// the types of the schema of the body.
+func (s *Server) formValue(schema *Schema, form url.Values) map[string]interface{} {
+	if schema.Ref != "" {
+		schema = s.openAPI.Components.Schemas[strings.TrimPrefix(schema.Ref, "#/components/schemas/")]
+	}
+	value := make(map[string]interface{}, len(form))
+	for name, fieldSchema := range schema.Properties {
+		if _, ok := form[name]; !ok {
+			continue
+		}
+		field := form.Get(name)
+		if field == "" && fieldSchema.Type != "string" {
+			// Missing, as empty fields are for Bind
+			continue
+		}
+		switch fieldSchema.Type {
+		case "integer", "number":
+			value[name] = json.Number(field)
+		case "boolean":
+			if b, err := strconv.ParseBool(field); err == nil {
+				value[name] = b
+			} else {
+				value[name] = field
+			}
+		case "string":
+			value[name] = field
+		}
+	}
+	return value
+}
+
+// operation returns the documented operation of a route, nil if there is none.
+// Legacy paths get the operation of their successor under the API prefix.
+func (s *Server) operation(method, path string) *Operation {
+	if s.openAPI == nil {
+		return nil
+	}
+	if op := s.openAPI.Paths[openAPIPath(path)][strings.ToLower(method)]; op != nil {
+		return op
+	}
+	return s.openAPI.Paths[openAPIPath(apiPrefix+path)][strings.ToLower(method)]
+}
+
+func validateQuery(op *Operation, query url.Values) error {
+	for _, param := range op.Parameters {
+		value := query.Get(param.Name)
+		if param.In != "query" || value == "" {
+			continue
+		}
+		switch param.Schema.Type {
+		case "integer":
+			if _, err := strconv.Atoi(value); err != nil {
+				return invalidField(param.Name, "must be an integer")
+			}
+		case "boolean":
+			if _, err := strconv.ParseBool(value); err != nil {
+				return invalidField(param.Name, "must be a boolean")
+			}
+		}
+		if len(param.Schema.Enum) > 0 && !contains(param.Schema.Enum, value) {
+			return invalidField(param.Name, "must be one of "+strings.Join(param.Schema.Enum, ", "))
+		}
+	}
+	return nil
+}
+
+// validateValue checks a decoded JSON value against a schema and returns an
+// error for the first field that does not match. Empty strings of optional
+// fields count as missing, as they do for the handlers.
+func (s *Server) validateValue(schema *Schema, value interface{}, path string) error {
+	if schema.Ref != "" {
+		name := strings.TrimPrefix(schema.Ref, "#/components/schemas/")
+		schema = s.openAPI.Components.Schemas[name]
+	}
+	if value == nil {
+		return nil
+	}
+
+	switch schema.Type {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return invalidField(path, "must be an object")
+		}
+		for _, name := range schema.Required {
+			if object[name] == nil {
+				return invalidField(fieldPath(path, name), "is required")
+			}
+		}
+		names := make([]string, 0, len(object))
+		for name := range object {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fieldSchema, ok := schema.Properties[name]
+			if !ok || (object[name] == "" && !contains(schema.Required, name)) {
+				continue
+			}
+			if err := s.validateValue(fieldSchema, object[name], fieldPath(path, name)); err != nil {
+				return err
+			}
+		}
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return invalidField(path, "must be an array")
+		}
+		for i, item := range items {
+			if err := s.validateValue(schema.Items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return invalidField(path, "must be a string")
+		}
+		length := utf8.RuneCountInString(str)
+		if schema.MinLength != nil && length < *schema.MinLength {
+			if *schema.MinLength == 1 {
+				return invalidField(path, "is required")
+			}
+			return invalidField(path, fmt.Sprintf("must be at least %d characters", *schema.MinLength))
+		}
+		if schema.MaxLength != nil && length > *schema.MaxLength {
+			return invalidField(path, fmt.Sprintf("must be at most %d characters", *schema.MaxLength))
+		}
+		if len(schema.Enum) > 0 && !contains(schema.Enum, str) {
+			return invalidField(path, "must be one of "+strings.Join(schema.Enum, ", "))
+		}
+	case "integer", "number":
+		number, ok := value.(json.Number)
+		if !ok {
+			return invalidField(path, "must be a number")
+		}
+		n, err := number.Int64()
+		if schema.Type == "integer" && err != nil {
+			return invalidField(path, "must be an integer")
+		}
+		if schema.Minimum != nil && err == nil && n < int64(*schema.Minimum) {
+			return invalidField(path, fmt.Sprintf("must be at least %d", *schema.Minimum))
+		}
+		if schema.Maximum != nil && err == nil && n > int64(*schema.Maximum) {
+			return invalidField(path, fmt.Sprintf("must be at most %d", *schema.Maximum))
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return invalidField(path, "must be a boolean")
+		}
+	}
+	return nil
+}
+
+func fieldPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func invalidField(field, reason string) error {
+	if field == "" {
+		field = "body"
+	}
+	return newAPIError(http.StatusBadRequest, CodeBadRequest, field+" "+reason).
+		WithDetail("field", field).
+		WithDetail("reason", reason)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+package web
+
+import (
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+)
+
+// Version of the server, set by the build with
+// -ldflags "-X bulut-server/internal/web.version=v1.2.0"
+var version = "dev"
+
+// Range of API versions the server serves, each under its /v<n> prefix
+const (
+	minAPIVersion = 1
+	maxAPIVersion = 1
+	apiPrefix     = "/v1"
+)
+
+type VersionResponse struct {
+	// Version of the server, dev for builds without one
+	Version string `json:"version"`
+	// Git commit the server was built from, empty if unknown
+	Commit    string   `json:"commit,omitempty"`
+	GoVersion string   `json:"go_version"`
+	API       APIRange `json:"api"`
+}
+
+// APIRange is the range of API versions a server serves. Clients check that
+// their version is in it before talking to the server.
+type APIRange struct {
+	Min int `json:"min"`
+	Max int `json:"max"`
+}
+
+var buildVersion = VersionResponse{
+	Version:   version,
+	Commit:    buildCommit(),
+	GoVersion: runtime.Version(),
+	API:       APIRange{Min: minAPIVersion, Max: maxAPIVersion},
+}
+
+// buildCommit returns the commit recorded by go build in a git checkout,
+// marked -dirty if there were uncommitted changes.
+func buildCommit() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	var commit string
+	var modified bool
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			commit = setting.Value
+		case "vcs.modified":
+			modified = setting.Value == "true"
+		}
+	}
+	if commit != "" && modified {
+		commit += "-dirty"
+	}
+	return commit
+}
+
+// Version returns the version of the server set by the build.
+func Version() string {
+	return version
+}
+
+func (s *Server) versionHandler(c echo.Context) error {
+	return c.JSON(http.StatusOK, buildVersion)
+}
+
+// legacyRoute marks responses of the unversioned paths as deprecated and
+// points to their successor under the current API version. They are served
+// until clients from before versioning are gone.
+func (s *Server) legacyRoute(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		header := c.Response().Header()
+		header.Set("Deprecation", "true")
+		header.Set("Link", "<"+apiPrefix+c.Request().URL.Path+`>; rel="successor-version"`)
+		return next(c)
+	}
+}
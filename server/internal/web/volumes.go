@@ -0,0 +1,183 @@
+package web
+
+import (
+	"bulut-server/internal/logic/deploy"
+	"bulut-server/internal/logic/snapshot"
+	"bulut-server/pkg/orm/models"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"net/http"
+)
+
+type VolumeRequest struct {
+	Name string `json:"name" validate:"required,max=63"`
+	// Absolute path the volume is mounted at in the containers
+	Path string `json:"path" validate:"required"`
+}
+
+type SetVolumesRequest struct {
+	Volumes []VolumeRequest `json:"volumes"`
+}
+
+type VolumesResponse struct {
+	Volumes []models.Volume `json:"volumes"`
+}
+
+type CreateSnapshotRequest struct {
+	Volume string `json:"volume" validate:"required"`
+}
+
+type RestoreSnapshotRequest struct {
+	// Volume to restore into, created if it does not exist. The volume of the
+	// snapshot if empty.
+	Volume string `json:"volume"`
+}
+
+type SnapshotListResponse struct {
+	Snapshots []snapshot.Snapshot `json:"snapshots"`
+}
+
+func (s *Server) listVolumesHandler(c echo.Context) error {
+	deployment, err := s.findDeployment(c)
+	if deployment == nil {
+		return err
+	}
+
+	volumes, err := deploy.FindVolumes(s.db, *deployment)
+	if err != nil {
+		s.log(c).Error(err, "Failed to find volumes")
+		return newAPIError(http.StatusInternalServerError, CodeInternal, "Failed to find volumes")
+	}
+	return c.JSON(http.StatusOK, VolumesResponse{Volumes: volumes})
+}
+
+func (s *Server) setVolumesHandler(c echo.Context) error {
+	var req SetVolumesRequest
+	if err := c.Bind(&req); err != nil {
+		return newAPIError(http.StatusBadRequest, CodeBadRequest, "Bad request")
+	}
+	volumes := make([]models.Volume, 0, len(req.Volumes))
+	for _, v := range req.Volumes {
+		volumes = append(volumes, models.Volume{Name: v.Name, Path: v.Path})
+	}
+
+	deployment, err := s.findDeployment(c)
+	if deployment == nil {
+		return err
+	}
+
+	err = deploy.SetVolumes(deploy.ReplicaOpts{
+		Db:      s.db,
+		Logger:  s.log(c),
+		Gateway: s.gateway,
+		Context: c.Request().Context(),
+	}, *deployment, volumes)
+	if err != nil {
+		if errors.Is(err, deploy.ErrInvalidVolume) {
+			return newAPIError(http.StatusBadRequest, CodeBadRequest, err.Error())
+		}
+		s.log(c).Error(err, "Failed to set volumes")
+		return newAPIError(http.StatusInternalServerError, CodeInternal, "Failed to set volumes")
+	}
+
+	return c.JSON(http.StatusOK, VolumesResponse{Volumes: volumes})
+}
+
+func (s *Server) listSnapshotsHandler(c echo.Context) error {
+	if s.snapshots == nil {
+		return newAPIError(http.StatusServiceUnavailable, CodeNotConfigured, "Snapshot storage is not available")
+	}
+	deployment, err := s.findDeployment(c)
+	if deployment == nil {
+		return err
+	}
+
+	snapshots, err := s.snapshots.List(deployment.ID.String(), c.QueryParam("volume"))
+	if err != nil {
+		s.log(c).Error(err, "Failed to list snapshots")
+		return newAPIError(http.StatusInternalServerError, CodeInternal, "Failed to list snapshots")
+	}
+	return c.JSON(http.StatusOK, SnapshotListResponse{Snapshots: snapshots})
+}
+
+func (s *Server) createSnapshotHandler(c echo.Context) error {
+	if s.snapshots == nil {
+		return newAPIError(http.StatusServiceUnavailable, CodeNotConfigured, "Snapshot storage is not available")
+	}
+	var req CreateSnapshotRequest
+	if err := c.Bind(&req); err != nil {
+		return newAPIError(http.StatusBadRequest, CodeBadRequest, "Bad request")
+	}
+
+	deployment, err := s.findDeployment(c)
+	if deployment == nil {
+		return err
+	}
+
+	snap, err := deploy.SnapshotVolume(deploy.ReplicaOpts{
+		Db:      s.db,
+		Logger:  s.log(c),
+		Gateway: s.gateway,
+		Context: c.Request().Context(),
+	}, s.snapshots, *deployment, req.Volume)
+	if err != nil {
+		if errors.Is(err, deploy.ErrVolumeNotFound) {
+			return newAPIError(http.StatusNotFound, CodeNotFound, "Volume not found")
+		}
+		if errors.Is(err, deploy.ErrInvalidVolume) {
+			return newAPIError(http.StatusBadRequest, CodeBadRequest, err.Error())
+		}
+		s.log(c).Error(err, "Failed to snapshot volume", "volume", req.Volume)
+		return newAPIError(http.StatusInternalServerError, CodeInternal, "Failed to snapshot volume")
+	}
+	return c.JSON(http.StatusCreated, snap)
+}
+
+func (s *Server) restoreSnapshotHandler(c echo.Context) error {
+	if s.snapshots == nil {
+		return newAPIError(http.StatusServiceUnavailable, CodeNotConfigured, "Snapshot storage is not available")
+	}
+	var req RestoreSnapshotRequest
+	if err := c.Bind(&req); err != nil {
+		return newAPIError(http.StatusBadRequest, CodeBadRequest, "Bad request")
+	}
+
+	deployment, err := s.findDeployment(c)
+	if deployment == nil {
+		return err
+	}
+
+	snap, err := deploy.RestoreSnapshot(deploy.ReplicaOpts{
+		Db:      s.db,
+		Logger:  s.log(c),
+		Gateway: s.gateway,
+		Context: c.Request().Context(),
+	}, s.snapshots, *deployment, c.Param("snapshot"), req.Volume)
+	if err != nil {
+		if errors.Is(err, snapshot.ErrNotFound) {
+			return newAPIError(http.StatusNotFound, CodeNotFound, "Snapshot not found")
+		}
+		if errors.Is(err, deploy.ErrDeploymentDeleted) {
+			return newAPIError(http.StatusNotFound, CodeNotFound, "Deployment not found")
+		}
+		if errors.Is(err, deploy.ErrInvalidVolume) {
+			return newAPIError(http.StatusBadRequest, CodeBadRequest, err.Error())
+		}
+		s.log(c).Error(err, "Failed to restore snapshot", "snapshot", c.Param("snapshot"))
+		return newAPIError(http.StatusInternalServerError, CodeInternal, "Failed to restore snapshot")
+	}
+	if req.Volume == "" {
+		req.Volume = snap.Volume
+	}
+	return c.JSON(http.StatusOK, RestoreSnapshotResponse{
+		Message:  "Snapshot restored successfully",
+		Snapshot: snap.ID,
+		Volume:   req.Volume,
+	})
+}
+
+type RestoreSnapshotResponse struct {
+	Message  string `json:"message"`
+	Snapshot string `json:"snapshot"`
+	Volume   string `json:"volume"`
+}
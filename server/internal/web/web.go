@@ -1,16 +1,28 @@
 package web
 
 import (
+	"bulut-server/internal/gateway"
+	"bulut-server/internal/logic/logs"
+	"bulut-server/internal/logic/oidc"
+	"bulut-server/internal/logic/snapshot"
 	"bulut-server/pkg/logger"
 	docker "github.com/fsouza/go-dockerclient"
 	"github.com/labstack/echo/v4"
 	"gorm.io/gorm"
+	"net"
+	"sync/atomic"
 )
 
 type ServerConfig struct {
 	Host   string
 	Port   int
 	ApiKey string
+	// Space the temp dir needs for the server to be ready, uploads are saved
+	// and built there
+	MinFreeDiskMB int
+	// Proxies whose X-Forwarded-For header is trusted for the client IP, the
+	// IP of the connection is used when empty
+	TrustedProxies []*net.IPNet
 }
 
 type Server struct {
@@ -18,6 +30,14 @@ type Server struct {
 	logger       *logger.Logger
 	db           *gorm.DB
 	dockerClient *docker.Client
+	gateway      *gateway.Gateway
+	logStore     *logs.Store
+	snapshots    *snapshot.Store
+	oidc         *oidc.Provider
+	// Built from the routes once they are configured
+	openAPI *OpenAPI
+	// Status of the last health probe, to log when it changes
+	readiness atomic.Value
 	*echo.Echo
 }
 
@@ -25,6 +45,11 @@ type ServerUtils struct {
 	Logger       *logger.Logger
 	DockerClient *docker.Client
 	Db           *gorm.DB
+	Gateway      *gateway.Gateway
+	LogStore     *logs.Store
+	Snapshots    *snapshot.Store
+	// Nil when SSO login is not configured
+	OIDC *oidc.Provider
 }
 
 func NewServer(config *ServerConfig, components ServerUtils) *Server {
@@ -33,10 +58,43 @@ func NewServer(config *ServerConfig, components ServerUtils) *Server {
 		logger:       components.Logger,
 		db:           components.Db,
 		dockerClient: components.DockerClient,
+		gateway:      components.Gateway,
+		logStore:     components.LogStore,
+		snapshots:    components.Snapshots,
+		oidc:         components.OIDC,
 		Echo:         echo.New(),
 	}
+	s.HTTPErrorHandler = s.errorHandler
+	s.IPExtractor = ipExtractor(config.TrustedProxies)
+	s.Use(s.requestIDMiddleware)
+	s.Use(s.metricsMiddleware)
+	s.Use(s.tracingMiddleware)
+	s.Use(s.accessLogMiddleware)
 	s.ConfigureRoutes()
+	s.openAPI = s.buildOpenAPI()
+	for _, route := range s.UndocumentedRoutes() {
+		s.logger.Warn("Route is missing from the OpenAPI document", "route", route)
+	}
 	// Disabled due to last params having a bug, an unwanted slash is added
 	//s.Echo.Pre(middleware.AddTrailingSlash())
 	return s
 }
+
+// ipExtractor returns the IP of the connection, or the client IP of
+// X-Forwarded-For for requests of trusted proxies, so clients cannot spoof
+// the source IP of audit entries and access logs.
+func ipExtractor(trustedProxies []*net.IPNet) echo.IPExtractor {
+	if len(trustedProxies) == 0 {
+		return echo.ExtractIPDirect()
+	}
+	// Only the configured proxies are trusted, not any private address
+	options := []echo.TrustOption{
+		echo.TrustLoopback(false),
+		echo.TrustLinkLocal(false),
+		echo.TrustPrivateNet(false),
+	}
+	for _, proxy := range trustedProxies {
+		options = append(options, echo.TrustIPRange(proxy))
+	}
+	return echo.ExtractIPFromXFFHeader(options...)
+}
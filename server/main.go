@@ -1,20 +1,39 @@
 package main
 
 import (
+	"bulut-server/internal/gateway"
+	"bulut-server/internal/logic/deploy"
+	"bulut-server/internal/logic/logs"
+	"bulut-server/internal/logic/oidc"
+	"bulut-server/internal/logic/snapshot"
+	"bulut-server/internal/metrics"
+	"bulut-server/internal/tracing"
 	"bulut-server/internal/web"
 	"bulut-server/pkg/config"
 	"bulut-server/pkg/logger"
 	"bulut-server/pkg/orm/common"
+	"bulut-server/pkg/orm/migrations"
+	"context"
+	"errors"
 	docker "github.com/fsouza/go-dockerclient"
 	"github.com/labstack/echo/v4/middleware"
 	"os"
 	"os/signal"
 	"strconv"
+	"syscall"
+	"time"
 )
 
 func main() {
 	config.Init()
 
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(migrate(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "openapi" {
+		os.Exit(openAPI())
+	}
+
 	webServerConfig := config.GetWebServerConfig()
 
 	log := logger.New(logger.Options{
@@ -22,20 +41,84 @@ func main() {
 		Level:       logger.InfoLevel,
 	})
 
+	shutdownTracing, err := tracing.Setup(config.GetTracingConfig())
+	if err != nil {
+		log.Error(err, "Failed to set up tracing")
+		os.Exit(1)
+	}
+
 	dbConfig := config.GetDatabaseConfig()
 	db, err := common.ConnectDB(dbConfig)
 	if err != nil {
 		log.Error(err, "Failed to connect to database")
+		os.Exit(1)
+	}
+	if err := migrations.Check(db); err != nil {
+		if !errors.Is(err, migrations.ErrSchemaOutdated) || !dbConfig.AutoMigrate {
+			log.Error(err, "Refusing to start")
+			os.Exit(1)
+		}
+		applied, err := migrations.Up(db)
+		if err != nil {
+			log.Error(err, "Failed to migrate database")
+			os.Exit(1)
+		}
+		for _, m := range applied {
+			log.Info("Applied migration", "version", m.Version, "name", m.Name)
+		}
 	}
 	dockerClient, err := docker.NewClientFromEnv()
 	if err != nil {
 		log.Error(err, "Failed to connect to docker")
 	}
+	snapshots, err := snapshot.NewStore(config.GetSnapshotConfig())
+	if err != nil {
+		log.Error(err, "Failed to open snapshot store")
+	}
+
+	gw := gateway.New(config.GetGatewayConfig(), log)
+	err = deploy.RestoreGatewayRoutes(deploy.ReplicaOpts{
+		Db:      db,
+		Logger:  log,
+		Gateway: gw,
+	})
+	if err != nil {
+		log.Error(err, "Failed to restore gateway routes")
+	}
+	if dockerClient != nil {
+		watcher := deploy.NewWatcher(deploy.ReplicaOpts{
+			Db:      db,
+			Logger:  log,
+			Gateway: gw,
+		}, dockerClient)
+		watcher.Start(context.Background())
+		metrics.NewContainerStats(db, log, dockerClient).Start(context.Background())
+	}
+	logStore, err := logs.NewStore(config.GetLogStoreConfig())
+	if err != nil {
+		log.Error(err, "Failed to open log store")
+	} else if dockerClient != nil {
+		logs.NewCollector(db, log, dockerClient, logStore).Start(context.Background())
+	}
+	go func() {
+		if err := gw.Start(); err != nil {
+			log.Error(err, "Failed to start gateway")
+		}
+	}()
+
+	var oidcProvider *oidc.Provider
+	if oidcConfig := config.GetOIDCConfig(); oidcConfig != nil {
+		oidcProvider = oidc.NewProvider(oidcConfig, nil)
+	}
 
 	server := web.NewServer(webServerConfig, web.ServerUtils{
 		Logger:       log,
 		DockerClient: dockerClient,
 		Db:           db,
+		Gateway:      gw,
+		LogStore:     logStore,
+		Snapshots:    snapshots,
+		OIDC:         oidcProvider,
 	})
 
 	// Add middleware for gracefully handling panics
@@ -44,13 +127,20 @@ func main() {
 	serverConfig := config.GetWebServerConfig()
 	portStr := strconv.Itoa(serverConfig.Port)
 	address := serverConfig.Host + ":" + portStr
-	err = server.Start(address)
-	if err != nil {
-		log.Error(err, "Failed to start server")
-	}
+	go func() {
+		if err := server.Start(address); err != nil {
+			log.Error(err, "Failed to start server")
+		}
+	}()
 
 	// graceful shutdown
 	stopChan := make(chan os.Signal, 1)
-	signal.Notify(stopChan, os.Interrupt)
+	signal.Notify(stopChan, os.Interrupt, syscall.SIGTERM)
 	<-stopChan
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdownTracing(ctx); err != nil {
+		log.Error(err, "Failed to flush traces")
+	}
 }
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"bulut-server/internal/web"
+	"bulut-server/pkg/logger"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// openAPI runs the openapi subcommand, which prints the OpenAPI document of
+// the API without starting the server. It fails if a route is undocumented,
+// so CI catches routes added without documentation.
+func openAPI() int {
+	server := web.NewServer(&web.ServerConfig{}, web.ServerUtils{
+		Logger: logger.New(logger.Options{Level: logger.ErrorLevel}),
+	})
+
+	if missing := server.UndocumentedRoutes(); len(missing) > 0 {
+		fmt.Fprintln(os.Stderr, "Routes missing from the OpenAPI document, add them to apiDocs:")
+		for _, route := range missing {
+			fmt.Fprintln(os.Stderr, "  "+route)
+		}
+		return 1
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(server.OpenAPI()); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
@@ -1,13 +1,23 @@
 package config
 
 import (
+	"bulut-server/internal/gateway"
+	"bulut-server/internal/logic/auth"
+	"bulut-server/internal/logic/logs"
+	"bulut-server/internal/logic/oidc"
+	"bulut-server/internal/logic/snapshot"
+	"bulut-server/internal/tracing"
 	"bulut-server/internal/web"
 	"bulut-server/pkg/orm/common"
+	"fmt"
 	"github.com/joho/godotenv"
 	"github.com/spf13/viper"
 	"log"
+	"net"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
 func Init() {
@@ -50,8 +60,10 @@ func GetWebServerConfig() *web.ServerConfig {
 	host := os.Getenv("HOST")
 	portStr := os.Getenv("PORT")
 	apiKey := os.Getenv("API_KEY")
+	minFreeDiskStr := os.Getenv("MIN_FREE_DISK_MB")
 
 	defaultPort := 8080
+	defaultMinFreeDisk := 512
 
 	port, err := strconv.Atoi(portStr)
 	if err != nil || port < 0 || port > 65535 {
@@ -63,19 +75,253 @@ func GetWebServerConfig() *web.ServerConfig {
 		log.Fatal("Missing API_KEY environment variable")
 	}
 
+	minFreeDisk := defaultMinFreeDisk
+	if minFreeDiskStr != "" {
+		minFreeDisk, err = strconv.Atoi(minFreeDiskStr)
+		if err != nil || minFreeDisk < 0 {
+			log.Fatalf("Invalid MIN_FREE_DISK_MB value: %s", minFreeDiskStr)
+		}
+	}
+
+	trustedProxies, err := parseTrustedProxies(os.Getenv("TRUSTED_PROXIES"))
+	if err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES value: %s", err)
+	}
+
 	return &web.ServerConfig{
-		Host:   host,
-		Port:   port,
-		ApiKey: apiKey,
+		Host:           host,
+		Port:           port,
+		ApiKey:         apiKey,
+		MinFreeDiskMB:  minFreeDisk,
+		TrustedProxies: trustedProxies,
 	}
 }
 
+// parseTrustedProxies parses a comma separated list of IPs and CIDR ranges.
+func parseTrustedProxies(value string) ([]*net.IPNet, error) {
+	var proxies []*net.IPNet
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if !strings.Contains(field, "/") {
+			ip := net.ParseIP(field)
+			if ip == nil {
+				return nil, fmt.Errorf("%s is not an IP or a CIDR range", field)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			proxies = append(proxies, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, proxy, err := net.ParseCIDR(field)
+		if err != nil {
+			return nil, fmt.Errorf("%s is not an IP or a CIDR range", field)
+		}
+		proxies = append(proxies, proxy)
+	}
+	return proxies, nil
+}
+
 func GetDatabaseConfig() *common.DatabaseConfig {
+	driver := os.Getenv("DB_DRIVER")
 	dbPath := os.Getenv("DB_PATH")
+	dsn := os.Getenv("DB_DSN")
+	if driver == "" {
+		driver = common.DriverSQLite
+	}
 	if dbPath == "" {
 		dbPath = "bulut.db"
 	}
+	if driver == common.DriverPostgres && dsn == "" {
+		log.Fatal("Missing DB_DSN environment variable for the postgres driver")
+	}
+	autoMigrate := true
+	if value := os.Getenv("DB_AUTO_MIGRATE"); value != "" {
+		var err error
+		autoMigrate, err = strconv.ParseBool(value)
+		if err != nil {
+			log.Fatalf("Invalid DB_AUTO_MIGRATE value: %s", value)
+		}
+	}
 	return &common.DatabaseConfig{
-		DBPath: dbPath,
+		Driver:      driver,
+		DBPath:      dbPath,
+		DSN:         dsn,
+		AutoMigrate: autoMigrate,
+	}
+}
+
+func GetGatewayConfig() *gateway.Config {
+	host := os.Getenv("GATEWAY_HOST")
+	portStr := os.Getenv("GATEWAY_PORT")
+	domain := os.Getenv("GATEWAY_DOMAIN")
+
+	defaultPort := 8000
+
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port < 0 || port > 65535 {
+		log.Printf("Invalid or missing gateway port value: %s. Using default port: %d\n", portStr, defaultPort)
+		port = defaultPort
+	}
+
+	if domain == "" {
+		domain = "localhost"
+	}
+
+	return &gateway.Config{
+		Host:   host,
+		Port:   port,
+		Domain: domain,
+	}
+}
+
+func GetLogStoreConfig() *logs.StoreConfig {
+	dir := os.Getenv("LOG_DIR")
+	maxMBStr := os.Getenv("LOG_MAX_MB_PER_DEPLOYMENT")
+
+	defaultMaxMB := 100
+
+	if dir == "" {
+		dir = "logs"
+	}
+
+	maxMB, err := strconv.Atoi(maxMBStr)
+	if err != nil || maxMB < 0 {
+		if maxMBStr != "" {
+			log.Printf("Invalid log size limit: %s. Using default limit: %d MB\n", maxMBStr, defaultMaxMB)
+		}
+		maxMB = defaultMaxMB
+	}
+
+	return &logs.StoreConfig{
+		Dir:                   dir,
+		MaxBytesPerDeployment: int64(maxMB) << 20,
+	}
+}
+
+func GetSnapshotConfig() *snapshot.StoreConfig {
+	dir := os.Getenv("SNAPSHOT_DIR")
+	retentionStr := os.Getenv("SNAPSHOT_RETENTION")
+	helperImage := os.Getenv("SNAPSHOT_HELPER_IMAGE")
+
+	defaultRetention := 7
+
+	if dir == "" {
+		dir = "snapshots"
+	}
+	if helperImage == "" {
+		helperImage = "busybox:1.36"
+	}
+
+	retention, err := strconv.Atoi(retentionStr)
+	if err != nil || retention < 0 {
+		if retentionStr != "" {
+			log.Printf("Invalid snapshot retention: %s. Using default retention: %d\n", retentionStr, defaultRetention)
+		}
+		retention = defaultRetention
+	}
+
+	return &snapshot.StoreConfig{
+		Dir:         dir,
+		Retention:   retention,
+		HelperImage: helperImage,
+	}
+}
+
+// GetOIDCConfig returns nil when OIDC_ISSUER is not set, which disables SSO login.
+func GetOIDCConfig() *oidc.Config {
+	issuer := os.Getenv("OIDC_ISSUER")
+	if issuer == "" {
+		return nil
+	}
+	clientID := os.Getenv("OIDC_CLIENT_ID")
+	if clientID == "" {
+		log.Fatal("Missing OIDC_CLIENT_ID environment variable")
+	}
+
+	scopes := strings.Fields(os.Getenv("OIDC_SCOPES"))
+	if len(scopes) == 0 {
+		scopes = []string{"openid", "profile", "email", "groups"}
+	}
+	usernameClaim := os.Getenv("OIDC_USERNAME_CLAIM")
+	if usernameClaim == "" {
+		usernameClaim = "preferred_username"
+	}
+	groupsClaim := os.Getenv("OIDC_GROUPS_CLAIM")
+	if groupsClaim == "" {
+		groupsClaim = "groups"
+	}
+	defaultScope := auth.Scope(os.Getenv("OIDC_DEFAULT_SCOPE"))
+	if defaultScope == "" {
+		defaultScope = auth.ScopeRead
+	}
+	if !defaultScope.Valid() {
+		log.Fatalf("Invalid OIDC_DEFAULT_SCOPE: %s", defaultScope)
+	}
+	rules, err := oidc.ParseRules(os.Getenv("OIDC_GROUP_RULES"))
+	if err != nil {
+		log.Fatalf("Invalid OIDC_GROUP_RULES: %s", err)
+	}
+
+	return &oidc.Config{
+		Issuer:        issuer,
+		ClientID:      clientID,
+		Scopes:        scopes,
+		UsernameClaim: usernameClaim,
+		GroupsClaim:   groupsClaim,
+		DefaultScope:  defaultScope,
+		Rules:         rules,
+		SessionTTL:    getDurationEnv("OIDC_SESSION_TTL", time.Hour),
+		RefreshTTL:    getDurationEnv("OIDC_REFRESH_TTL", 30*24*time.Hour),
+	}
+}
+
+// GetTracingConfig returns the tracing config, with an empty exporter when
+// TRACING_EXPORTER is not set, which disables tracing.
+func GetTracingConfig() *tracing.Config {
+	exporter := os.Getenv("TRACING_EXPORTER")
+	file := os.Getenv("TRACING_FILE")
+	ratioStr := os.Getenv("TRACING_SAMPLE_RATIO")
+
+	switch exporter {
+	case "", tracing.ExporterOTLP, tracing.ExporterStdout, tracing.ExporterFile:
+	default:
+		log.Fatalf("Invalid TRACING_EXPORTER: %s", exporter)
+	}
+	if file == "" {
+		file = "traces.jsonl"
+	}
+
+	ratio := 1.0
+	if ratioStr != "" {
+		var err error
+		ratio, err = strconv.ParseFloat(ratioStr, 64)
+		if err != nil || ratio < 0 || ratio > 1 {
+			log.Fatalf("Invalid TRACING_SAMPLE_RATIO value: %s", ratioStr)
+		}
+	}
+
+	return &tracing.Config{
+		Exporter:       exporter,
+		File:           file,
+		SampleRatio:    ratio,
+		ServiceVersion: web.Version(),
+	}
+}
+
+func getDurationEnv(name string, defaultValue time.Duration) time.Duration {
+	valueStr := os.Getenv(name)
+	if valueStr == "" {
+		return defaultValue
+	}
+	value, err := time.ParseDuration(valueStr)
+	if err != nil || value <= 0 {
+		log.Printf("Invalid %s value: %s. Using default: %s\n", name, valueStr, defaultValue)
+		return defaultValue
 	}
+	return value
 }
@@ -81,3 +81,8 @@ func New(opts Options) *Logger {
 func (l *Logger) Warn(msg string, keysAndValues ...interface{}) {
 	l.Logger.V(0).Info(msg, keysAndValues...)
 }
+
+// With returns a logger that adds the given key-value pairs to every entry.
+func (l *Logger) With(keysAndValues ...interface{}) *Logger {
+	return &Logger{Logger: l.Logger.WithValues(keysAndValues...)}
+}
@@ -0,0 +1,10 @@
+package common
+
+import "gorm.io/gorm"
+
+// Errors of database operations, the same for every driver. Match them with
+// errors.Is instead of comparing driver messages.
+var (
+	ErrNotFound  = gorm.ErrRecordNotFound
+	ErrDuplicate = gorm.ErrDuplicatedKey
+)
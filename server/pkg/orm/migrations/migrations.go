@@ -0,0 +1,179 @@
+package migrations
+
+import (
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"sort"
+	"time"
+)
+
+var (
+	ErrSchemaTooNew   = errors.New("database schema is newer than this version of bulut-server")
+	ErrSchemaOutdated = errors.New("database schema is outdated, run `bulut-server migrate up`")
+)
+
+// Migration changes the schema from Version-1 to Version. Up and Down run in a
+// transaction together with the update of the schema_version table.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// All migrations, registered by the files of this package
+var migrations []Migration
+
+func register(m Migration) {
+	migrations = append(migrations, m)
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+}
+
+// SchemaVersion is a row of the schema_version table, one per applied
+// migration.
+type SchemaVersion struct {
+	Version   int `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+func (SchemaVersion) TableName() string {
+	return "schema_version"
+}
+
+// Latest returns the version the schema has after all migrations.
+func Latest() int {
+	if len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].Version
+}
+
+func ensureVersionTable(db *gorm.DB) error {
+	return db.AutoMigrate(&SchemaVersion{})
+}
+
+// Current returns the version of the database schema, 0 when no migration
+// was applied yet.
+func Current(db *gorm.DB) (int, error) {
+	if err := ensureVersionTable(db); err != nil {
+		return 0, err
+	}
+	var version int
+	err := db.Model(&SchemaVersion{}).Select("COALESCE(MAX(version), 0)").Scan(&version).Error
+	return version, err
+}
+
+// Check returns ErrSchemaTooNew when the database was migrated by a newer
+// bulut-server and ErrSchemaOutdated when migrations are pending.
+func Check(db *gorm.DB) error {
+	current, err := Current(db)
+	if err != nil {
+		return err
+	}
+	if current > Latest() {
+		return fmt.Errorf("%w: database is at version %d, latest known is %d", ErrSchemaTooNew, current, Latest())
+	}
+	if current < Latest() {
+		return fmt.Errorf("%w: database is at version %d, latest is %d", ErrSchemaOutdated, current, Latest())
+	}
+	return nil
+}
+
+// Up applies all pending migrations and returns them.
+func Up(db *gorm.DB) ([]Migration, error) {
+	current, err := Current(db)
+	if err != nil {
+		return nil, err
+	}
+	if current > Latest() {
+		return nil, fmt.Errorf("%w: database is at version %d, latest known is %d", ErrSchemaTooNew, current, Latest())
+	}
+
+	var applied []Migration
+	for _, m := range migrations {
+		if m.Version <= current {
+			continue
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&SchemaVersion{
+				Version:   m.Version,
+				Name:      m.Name,
+				AppliedAt: time.Now(),
+			}).Error
+		})
+		if err != nil {
+			return applied, fmt.Errorf("migration %d (%s) failed: %w", m.Version, m.Name, err)
+		}
+		applied = append(applied, m)
+	}
+	return applied, nil
+}
+
+// Down reverts the last steps applied migrations and returns them.
+func Down(db *gorm.DB, steps int) ([]Migration, error) {
+	current, err := Current(db)
+	if err != nil {
+		return nil, err
+	}
+	if current > Latest() {
+		return nil, fmt.Errorf("%w: database is at version %d, latest known is %d", ErrSchemaTooNew, current, Latest())
+	}
+
+	var reverted []Migration
+	for i := len(migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+		m := migrations[i]
+		if m.Version > current {
+			continue
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Down(tx); err != nil {
+				return err
+			}
+			return tx.Delete(&SchemaVersion{}, "version = ?", m.Version).Error
+		})
+		if err != nil {
+			return reverted, fmt.Errorf("reverting migration %d (%s) failed: %w", m.Version, m.Name, err)
+		}
+		reverted = append(reverted, m)
+	}
+	return reverted, nil
+}
+
+type Status struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time
+}
+
+// List returns all known migrations with the time they were applied, if they
+// were.
+func List(db *gorm.DB) ([]Status, error) {
+	if err := ensureVersionTable(db); err != nil {
+		return nil, err
+	}
+	var versions []SchemaVersion
+	if err := db.Order("version asc").Find(&versions).Error; err != nil {
+		return nil, err
+	}
+	appliedAt := make(map[int]time.Time, len(versions))
+	for _, v := range versions {
+		appliedAt[v.Version] = v.AppliedAt
+	}
+
+	statuses := make([]Status, 0, len(migrations))
+	for _, m := range migrations {
+		status := Status{Version: m.Version, Name: m.Name}
+		if t, ok := appliedAt[m.Version]; ok {
+			status.AppliedAt = &t
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
@@ -0,0 +1,127 @@
+package migrations_test
+
+import (
+	"bulut-server/pkg/orm/common"
+	"bulut-server/pkg/orm/migrations"
+	"bulut-server/pkg/orm/models"
+	"errors"
+	"gorm.io/gorm"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// forEachDatabase runs the test on an empty SQLite database, and on the
+// Postgres database of DB_DSN if it is set. The tests migrate that database
+// all the way down, so it must be one that can be wiped.
+func forEachDatabase(t *testing.T, test func(t *testing.T, db *gorm.DB)) {
+	configs := map[string]*common.DatabaseConfig{
+		common.DriverSQLite: {Driver: common.DriverSQLite, DBPath: filepath.Join(t.TempDir(), "bulut.db")},
+	}
+	if dsn := os.Getenv("DB_DSN"); dsn != "" {
+		configs[common.DriverPostgres] = &common.DatabaseConfig{Driver: common.DriverPostgres, DSN: dsn}
+	}
+	for _, driver := range []string{common.DriverSQLite, common.DriverPostgres} {
+		config, ok := configs[driver]
+		t.Run(driver, func(t *testing.T) {
+			if !ok {
+				t.Skip("DB_DSN is not set")
+			}
+			db, err := common.ConnectDB(config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			reset := func() {
+				if _, err := migrations.Down(db, migrations.Latest()); err != nil {
+					t.Fatal(err)
+				}
+			}
+			reset()
+			t.Cleanup(reset)
+			test(t, db)
+		})
+	}
+}
+
+func TestUpAndDown(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, db *gorm.DB) {
+		if err := migrations.Check(db); !errors.Is(err, migrations.ErrSchemaOutdated) {
+			t.Fatalf("expected an empty database to be outdated, got %v", err)
+		}
+
+		for i := 0; i < 2; i++ {
+			applied, err := migrations.Up(db)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(applied) != migrations.Latest() {
+				t.Fatalf("expected %d migrations to be applied, got %d", migrations.Latest(), len(applied))
+			}
+			if err := migrations.Check(db); err != nil {
+				t.Fatal(err)
+			}
+			for _, table := range []string{"deployments", "volumes", "refresh_tokens"} {
+				if !db.Migrator().HasTable(table) {
+					t.Fatalf("table %s is missing after migrating up", table)
+				}
+			}
+
+			// Down must undo everything so the next round applies cleanly
+			reverted, err := migrations.Down(db, migrations.Latest())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(reverted) != migrations.Latest() {
+				t.Fatalf("expected %d migrations to be reverted, got %d", migrations.Latest(), len(reverted))
+			}
+			if current, err := migrations.Current(db); err != nil || current != 0 {
+				t.Fatalf("expected version 0 after migrating down, got %d (%v)", current, err)
+			}
+			if db.Migrator().HasTable("deployments") {
+				t.Fatal("table deployments is left after migrating down")
+			}
+		}
+	})
+}
+
+func TestErrorTranslation(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, db *gorm.DB) {
+		if _, err := migrations.Up(db); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := db.Create(&models.User{Name: "alice"}).Error; err != nil {
+			t.Fatal(err)
+		}
+		err := db.Create(&models.User{Name: "alice"}).Error
+		if !errors.Is(err, common.ErrDuplicate) {
+			t.Fatalf("expected ErrDuplicate for a duplicate user, got %v", err)
+		}
+
+		var user models.User
+		err = db.Where("name = ?", "bob").First(&user).Error
+		if !errors.Is(err, common.ErrNotFound) {
+			t.Fatalf("expected ErrNotFound for a missing user, got %v", err)
+		}
+
+		// Names of deleted namespaces can be used again
+		namespace := models.Namespace{Name: "web"}
+		if err := db.Create(&namespace).Error; err != nil {
+			t.Fatal(err)
+		}
+		err = db.Create(&models.Namespace{Name: "web"}).Error
+		if !errors.Is(err, common.ErrDuplicate) {
+			t.Fatalf("expected ErrDuplicate for a duplicate namespace, got %v", err)
+		}
+		if err := db.Delete(&namespace).Error; err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Create(&models.Namespace{Name: "web"}).Error; err != nil {
+			t.Fatalf("expected the name of a deleted namespace to be free, got %v", err)
+		}
+		// Migration 2 cannot be reverted while names are used twice
+		if err := db.Unscoped().Delete(&namespace).Error; err != nil {
+			t.Fatal(err)
+		}
+	})
+}
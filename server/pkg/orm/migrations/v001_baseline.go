@@ -0,0 +1,157 @@
+package migrations
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"time"
+)
+
+// The schema as created by AutoMigrate before migrations were versioned.
+// Databases created back then are brought in line by AutoMigrate, new ones are
+// created from scratch. The structs are copies of the models at that time and
+// must not change with the models.
+
+type v1BaseModel struct {
+	ID        uuid.UUID `gorm:"primary_key"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt *time.Time `sql:"index"`
+}
+
+type v1Namespace struct {
+	Base        v1BaseModel    `gorm:"embedded"`
+	Name        string         `gorm:"unique;not null"`
+	Deployments []v1Deployment `gorm:"foreignKey:NamespaceID"`
+}
+
+func (v1Namespace) TableName() string { return "namespaces" }
+
+type v1Deployment struct {
+	Base              v1BaseModel `gorm:"embedded"`
+	Name              string      `gorm:"unique;not null"`
+	ContainerID       string
+	Replicas          int    `gorm:"not null;default:1"`
+	Balancer          string `gorm:"not null;default:round-robin"`
+	RestartPolicy     string `gorm:"not null;default:no"`
+	RestartMaxRetries int
+	RestartCount      int
+	LastExitCode      int
+	LastExitReason    string
+	LastExitAt        *time.Time
+	CrashLooping      bool
+	NamespaceID       uuid.UUID     `gorm:"not null"`
+	Namespace         v1Namespace   `gorm:"foreignKey:NamespaceID"`
+	Revisions         []v1Revision  `gorm:"foreignKey:DeploymentID"`
+	Containers        []v1Container `gorm:"foreignKey:DeploymentID"`
+}
+
+func (v1Deployment) TableName() string { return "deployments" }
+
+type v1Revision struct {
+	Base         v1BaseModel  `gorm:"embedded"`
+	DeploymentID uuid.UUID    `gorm:"not null"`
+	Deployment   v1Deployment `gorm:"foreignKey:DeploymentID"`
+	ImageName    string       `gorm:"not null"`
+	ImageTag     string       `gorm:"not null"`
+	ImageID      string       `gorm:"not null"`
+	Notes        string
+}
+
+func (v1Revision) TableName() string { return "revisions" }
+
+type v1Container struct {
+	Base         v1BaseModel `gorm:"embedded"`
+	DeploymentID uuid.UUID   `gorm:"not null"`
+	RevisionID   uuid.UUID   `gorm:"not null"`
+	ContainerID  string      `gorm:"not null"`
+	Address      string      `gorm:"not null"`
+}
+
+func (v1Container) TableName() string { return "containers" }
+
+type v1Volume struct {
+	Base         v1BaseModel `gorm:"embedded"`
+	DeploymentID uuid.UUID   `gorm:"not null"`
+	Name         string      `gorm:"not null"`
+	Path         string      `gorm:"not null"`
+}
+
+func (v1Volume) TableName() string { return "volumes" }
+
+type v1User struct {
+	Base    v1BaseModel  `gorm:"embedded"`
+	Name    string       `gorm:"unique;not null"`
+	Subject *string      `gorm:"unique"`
+	Tokens  []v1ApiToken `gorm:"foreignKey:UserID"`
+}
+
+func (v1User) TableName() string { return "users" }
+
+type v1ApiToken struct {
+	Base       v1BaseModel `gorm:"embedded"`
+	UserID     uuid.UUID   `gorm:"not null"`
+	User       v1User      `gorm:"foreignKey:UserID"`
+	Name       string
+	Prefix     string `gorm:"not null"`
+	Hash       string `gorm:"unique;not null"`
+	Scopes     string `gorm:"not null"`
+	ExpiresAt  *time.Time
+	RevokedAt  *time.Time
+	LastUsedAt *time.Time
+}
+
+func (v1ApiToken) TableName() string { return "api_tokens" }
+
+type v1NamespaceMember struct {
+	Base        v1BaseModel `gorm:"embedded"`
+	NamespaceID uuid.UUID   `gorm:"not null;uniqueIndex:idx_namespace_member"`
+	UserID      uuid.UUID   `gorm:"not null;uniqueIndex:idx_namespace_member"`
+	User        v1User      `gorm:"foreignKey:UserID"`
+	Role        string      `gorm:"not null"`
+}
+
+func (v1NamespaceMember) TableName() string { return "namespace_members" }
+
+type v1AuditEntry struct {
+	Base       v1BaseModel `gorm:"embedded"`
+	Actor      string      `gorm:"not null;index"`
+	UserID     *uuid.UUID
+	TokenID    *uuid.UUID
+	Namespace  string `gorm:"index"`
+	Deployment string `gorm:"index"`
+	Action     string `gorm:"not null;index"`
+	Payload    string
+	Status     int
+	Result     string `gorm:"not null"`
+	Error      string
+	SourceIP   string
+	RevisionID *uuid.UUID
+}
+
+func (v1AuditEntry) TableName() string { return "audit_entries" }
+
+type v1RefreshToken struct {
+	Base      v1BaseModel `gorm:"embedded"`
+	UserID    uuid.UUID   `gorm:"not null"`
+	User      v1User      `gorm:"foreignKey:UserID"`
+	Hash      string      `gorm:"unique;not null"`
+	Scopes    string      `gorm:"not null"`
+	ExpiresAt time.Time   `gorm:"not null"`
+	RevokedAt *time.Time
+}
+
+func (v1RefreshToken) TableName() string { return "refresh_tokens" }
+
+func init() {
+	register(Migration{
+		Version: 1,
+		Name:    "baseline",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&v1Namespace{}, &v1Deployment{}, &v1Revision{}, &v1Container{}, &v1Volume{}, &v1User{}, &v1ApiToken{}, &v1NamespaceMember{}, &v1AuditEntry{}, &v1RefreshToken{})
+		},
+		Down: func(tx *gorm.DB) error {
+			// Dependent tables first
+			return tx.Migrator().DropTable(&v1RefreshToken{}, &v1AuditEntry{}, &v1NamespaceMember{}, &v1ApiToken{}, &v1Volume{}, &v1Container{}, &v1Revision{}, &v1Deployment{}, &v1User{}, &v1Namespace{})
+		},
+	})
+}
@@ -0,0 +1,149 @@
+package migrations
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Makes soft deletes work and names unique only among rows that are not
+// deleted, so deployment names are unique per namespace and names of deleted
+// namespaces and deployments can be reused.
+
+type v2Namespace struct {
+	Name      string         `gorm:"not null;uniqueIndex:idx_namespaces_name,where:deleted_at IS NULL"`
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+func (v2Namespace) TableName() string { return "namespaces" }
+
+type v2Deployment struct {
+	Name        string         `gorm:"not null;uniqueIndex:idx_deployments_namespace_name,where:deleted_at IS NULL"`
+	NamespaceID uuid.UUID      `gorm:"not null;uniqueIndex:idx_deployments_namespace_name,where:deleted_at IS NULL"`
+	DeletedAt   gorm.DeletedAt `gorm:"index"`
+}
+
+func (v2Deployment) TableName() string { return "deployments" }
+
+type v2NamespaceMember struct {
+	NamespaceID uuid.UUID      `gorm:"not null;uniqueIndex:idx_namespace_member,where:deleted_at IS NULL"`
+	UserID      uuid.UUID      `gorm:"not null;uniqueIndex:idx_namespace_member,where:deleted_at IS NULL"`
+	DeletedAt   gorm.DeletedAt `gorm:"index"`
+}
+
+func (v2NamespaceMember) TableName() string { return "namespace_members" }
+
+type v2Volume struct {
+	DeploymentID uuid.UUID      `gorm:"not null;uniqueIndex:idx_volumes_deployment_name,where:deleted_at IS NULL"`
+	Name         string         `gorm:"not null;uniqueIndex:idx_volumes_deployment_name,where:deleted_at IS NULL"`
+	DeletedAt    gorm.DeletedAt `gorm:"index"`
+}
+
+func (v2Volume) TableName() string { return "volumes" }
+
+// Tables that only get an index on deleted_at, migrated with Table
+type v2SoftDelete struct {
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+var v2SoftDeleteTables = []string{"revisions", "containers", "users", "api_tokens", "audit_entries", "refresh_tokens"}
+
+// The unique column constraints of the baseline
+type v1UniqueNamespace struct {
+	Name string `gorm:"unique;not null"`
+}
+
+func (v1UniqueNamespace) TableName() string { return "namespaces" }
+
+type v1UniqueDeployment struct {
+	Name string `gorm:"unique;not null"`
+}
+
+func (v1UniqueDeployment) TableName() string { return "deployments" }
+
+type v1UniqueNamespaceMember struct {
+	NamespaceID uuid.UUID `gorm:"not null;uniqueIndex:idx_namespace_member"`
+	UserID      uuid.UUID `gorm:"not null;uniqueIndex:idx_namespace_member"`
+}
+
+func (v1UniqueNamespaceMember) TableName() string { return "namespace_members" }
+
+// alterUnique adds or removes the unique constraint of a column. SQLite can
+// only do that by recreating the table, which also drops its indexes.
+func alterUnique(tx *gorm.DB, model interface{}, table, column string, unique bool) error {
+	if tx.Dialector.Name() == "postgres" {
+		// Default name of a unique column constraint
+		constraint := fmt.Sprintf("%s_%s_key", table, column)
+		if unique {
+			return tx.Exec(fmt.Sprintf(`ALTER TABLE %q ADD CONSTRAINT %q UNIQUE (%q)`, table, constraint, column)).Error
+		}
+		return tx.Exec(fmt.Sprintf(`ALTER TABLE %q DROP CONSTRAINT IF EXISTS %q`, table, constraint)).Error
+	}
+	return tx.Migrator().AlterColumn(model, column)
+}
+
+// v2Indexes are the indexes added by the migration. They are created one by
+// one because AutoMigrate would turn the single column unique index of
+// namespaces back into a unique column.
+var v2Indexes = []struct {
+	model interface{}
+	name  string
+}{
+	{&v2Namespace{}, "idx_namespaces_name"},
+	{&v2Namespace{}, "DeletedAt"},
+	{&v2Deployment{}, "idx_deployments_namespace_name"},
+	{&v2Deployment{}, "DeletedAt"},
+	{&v2NamespaceMember{}, "idx_namespace_member"},
+	{&v2NamespaceMember{}, "DeletedAt"},
+	{&v2Volume{}, "idx_volumes_deployment_name"},
+	{&v2Volume{}, "DeletedAt"},
+}
+
+func init() {
+	register(Migration{
+		Version: 2,
+		Name:    "soft_delete_and_scoped_names",
+		Up: func(tx *gorm.DB) error {
+			if err := alterUnique(tx, &v2Namespace{}, "namespaces", "name", false); err != nil {
+				return err
+			}
+			if err := alterUnique(tx, &v2Deployment{}, "deployments", "name", false); err != nil {
+				return err
+			}
+			if err := tx.Migrator().DropIndex(&v1UniqueNamespaceMember{}, "idx_namespace_member"); err != nil {
+				return err
+			}
+			for _, index := range v2Indexes {
+				if err := tx.Migrator().CreateIndex(index.model, index.name); err != nil {
+					return err
+				}
+			}
+			for _, table := range v2SoftDeleteTables {
+				if err := tx.Table(table).Migrator().CreateIndex(&v2SoftDelete{}, "DeletedAt"); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			for _, table := range v2SoftDeleteTables {
+				if err := tx.Table(table).Migrator().DropIndex(&v2SoftDelete{}, "DeletedAt"); err != nil {
+					return err
+				}
+			}
+			for _, index := range v2Indexes {
+				if err := tx.Migrator().DropIndex(index.model, index.name); err != nil {
+					return err
+				}
+			}
+			// Fails when names are used more than once, like by deleted rows
+			if err := alterUnique(tx, &v1UniqueNamespace{}, "namespaces", "name", true); err != nil {
+				return err
+			}
+			if err := alterUnique(tx, &v1UniqueDeployment{}, "deployments", "name", true); err != nil {
+				return err
+			}
+			return tx.Migrator().CreateIndex(&v1UniqueNamespaceMember{}, "idx_namespace_member")
+		},
+	})
+}
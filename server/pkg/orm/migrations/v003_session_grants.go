@@ -0,0 +1,47 @@
+package migrations
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Links refresh tokens to the session token they were issued with and keeps
+// the groups of the user, so a refresh revokes the old session and computes
+// the grants again.
+
+type v3RefreshToken struct {
+	ApiTokenID *uuid.UUID
+	Groups     string
+}
+
+func (v3RefreshToken) TableName() string { return "refresh_tokens" }
+
+var v3Columns = []string{"ApiTokenID", "Groups"}
+
+func init() {
+	register(Migration{
+		Version: 3,
+		Name:    "session_grants",
+		Up: func(tx *gorm.DB) error {
+			for _, column := range v3Columns {
+				if err := tx.Migrator().AddColumn(&v3RefreshToken{}, column); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			for _, column := range v3Columns {
+				if err := tx.Migrator().DropColumn(&v3RefreshToken{}, column); err != nil {
+					return err
+				}
+			}
+			// SQLite drops columns by recreating the table, without its indexes
+			migrator := tx.Table("refresh_tokens").Migrator()
+			if !migrator.HasIndex(&v2SoftDelete{}, "DeletedAt") {
+				return migrator.CreateIndex(&v2SoftDelete{}, "DeletedAt")
+			}
+			return nil
+		},
+	})
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+type ApiToken struct {
+	BaseModel
+	UserID uuid.UUID `gorm:"not null" json:"user_id"`
+	User   User      `json:"user"`
+	Name   string    `json:"name"`
+	// First characters of the token, to tell tokens apart without the secret
+	Prefix string `gorm:"not null" json:"prefix"`
+	// SHA-256 of the token, the token itself is never stored
+	Hash       string     `gorm:"unique;not null" json:"-"`
+	Scopes     string     `gorm:"not null" json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
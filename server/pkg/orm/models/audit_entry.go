@@ -0,0 +1,34 @@
+package models
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var ErrAuditAppendOnly = errors.New("audit entries cannot be changed")
+
+// AuditEntry records a mutating API request. Entries are append-only.
+type AuditEntry struct {
+	BaseModel
+	Actor      string     `gorm:"not null;index" json:"actor"`
+	UserID     *uuid.UUID `json:"user_id"`
+	TokenID    *uuid.UUID `json:"token_id"`
+	Namespace  string     `gorm:"index" json:"namespace"`
+	Deployment string     `gorm:"index" json:"deployment"`
+	Action     string     `gorm:"not null;index" json:"action"`
+	Payload    string     `json:"payload"`
+	Status     int        `json:"status"`
+	Result     string     `gorm:"not null" json:"result"`
+	Error      string     `json:"error,omitempty"`
+	SourceIP   string     `json:"source_ip"`
+	RevisionID *uuid.UUID `json:"revision_id,omitempty"`
+}
+
+func (e *AuditEntry) BeforeUpdate(tx *gorm.DB) error {
+	return ErrAuditAppendOnly
+}
+
+func (e *AuditEntry) BeforeDelete(tx *gorm.DB) error {
+	return ErrAuditAppendOnly
+}
@@ -6,14 +6,22 @@ import (
 	"time"
 )
 
+// BaseModel is embedded by all models. Deleting a model only sets DeletedAt,
+// queries leave out deleted rows unless they are Unscoped.
 type BaseModel struct {
-	ID        uuid.UUID  `gorm:"primary_key" json:"id"`
-	CreatedAt time.Time  `json:"created_at"`
-	UpdatedAt time.Time  `json:"updated_at"`
-	DeletedAt *time.Time `sql:"index" json:"deleted_at"`
+	ID        uuid.UUID      `gorm:"primary_key" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at"`
 }
 
+// BeforeCreate gives new models an ID. Models that already have one keep it,
+// GORM upserts loaded associations on updates and a new ID would point the
+// parent at a row that is never inserted.
 func (base *BaseModel) BeforeCreate(tx *gorm.DB) error {
+	if base.ID != uuid.Nil {
+		return nil
+	}
 	id, err := uuid.NewUUID()
 	if err != nil {
 		return err
@@ -0,0 +1,12 @@
+package models
+
+import "github.com/google/uuid"
+
+// Container is a running replica of a deployment.
+type Container struct {
+	BaseModel
+	DeploymentID uuid.UUID `gorm:"not null"`
+	RevisionID   uuid.UUID `gorm:"not null"`
+	ContainerID  string    `gorm:"not null"`
+	Address      string    `gorm:"not null"`
+}
@@ -1,12 +1,30 @@
 package models
 
-import "github.com/google/uuid"
+import (
+	"github.com/google/uuid"
+	"time"
+)
 
 type Deployment struct {
 	BaseModel
-	Name        string `gorm:"unique;not null"`
+	// Unique within the namespace among deployments that are not deleted
+	Name string `gorm:"not null;uniqueIndex:idx_deployments_namespace_name,where:deleted_at IS NULL"`
+	// Deprecated: Containers holds the replicas, this is only read to clean up
+	// containers created before replicas were supported
 	ContainerID string
-	NamespaceID uuid.UUID `gorm:"not null"`
-	Namespace   Namespace `gorm:"foreignKey:NamespaceID"`
-	Revisions   []Revision
+	Replicas    int    `gorm:"not null;default:1"`
+	Balancer    string `gorm:"not null;default:round-robin"`
+	// Restart policy of the containers: no, on-failure or always
+	RestartPolicy     string `gorm:"not null;default:no"`
+	RestartMaxRetries int
+	// Crash tracking, filled in from Docker events
+	RestartCount   int
+	LastExitCode   int
+	LastExitReason string
+	LastExitAt     *time.Time
+	CrashLooping   bool
+	NamespaceID    uuid.UUID `gorm:"not null;uniqueIndex:idx_deployments_namespace_name,where:deleted_at IS NULL"`
+	Namespace      Namespace `gorm:"foreignKey:NamespaceID"`
+	Revisions      []Revision
+	Containers     []Container
 }
@@ -2,6 +2,7 @@ package models
 
 type Namespace struct {
 	BaseModel
-	Name        string       `gorm:"unique;not null"`
+	// Unique among namespaces that are not deleted
+	Name        string       `gorm:"not null;uniqueIndex:idx_namespaces_name,where:deleted_at IS NULL"`
 	Deployments []Deployment `gorm:"foreignKey:NamespaceID"`
 }
@@ -0,0 +1,12 @@
+package models
+
+import "github.com/google/uuid"
+
+type NamespaceMember struct {
+	BaseModel
+	NamespaceID uuid.UUID `gorm:"not null;uniqueIndex:idx_namespace_member,where:deleted_at IS NULL" json:"namespace_id"`
+	UserID      uuid.UUID `gorm:"not null;uniqueIndex:idx_namespace_member,where:deleted_at IS NULL" json:"user_id"`
+	User        User      `json:"user"`
+	// viewer, deployer or owner
+	Role string `gorm:"not null" json:"role"`
+}
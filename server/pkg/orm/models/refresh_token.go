@@ -0,0 +1,21 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// RefreshToken is exchanged for a new short-lived API token after SSO login.
+type RefreshToken struct {
+	BaseModel
+	UserID uuid.UUID `gorm:"not null"`
+	User   User
+	// Session token issued with the refresh token, revoked when it is redeemed
+	ApiTokenID *uuid.UUID
+	Hash       string `gorm:"unique;not null"`
+	Scopes     string `gorm:"not null"`
+	// Groups of the user at login, the grants are computed again from them
+	Groups    []string  `gorm:"serializer:json"`
+	ExpiresAt time.Time `gorm:"not null"`
+	RevokedAt *time.Time
+}
@@ -0,0 +1,9 @@
+package models
+
+type User struct {
+	BaseModel
+	Name string `gorm:"unique;not null" json:"name"`
+	// Subject of the user at the OIDC issuer, for users that log in with SSO
+	Subject *string    `gorm:"unique" json:"-"`
+	Tokens  []ApiToken `json:"-"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import "github.com/google/uuid"
+
+// Volume is a Docker volume mounted into every replica of a deployment. It
+// outlives the containers, so data is kept across deploys.
+type Volume struct {
+	BaseModel
+	DeploymentID uuid.UUID `gorm:"not null;uniqueIndex:idx_volumes_deployment_name,where:deleted_at IS NULL" json:"-"`
+	// Unique within the deployment among volumes that are not deleted
+	Name string `gorm:"not null;uniqueIndex:idx_volumes_deployment_name,where:deleted_at IS NULL" json:"name"`
+	// Absolute path the volume is mounted at in the containers
+	Path string `gorm:"not null" json:"path"`
+}